@@ -3,17 +3,51 @@ package glsp
 import (
 	contextpkg "context"
 	"encoding/json"
+	"time"
 )
 
 type NotifyFunc func(method string, params any)
 type CallFunc func(method string, params any, result any)
 
+// Cancelling the context of a call will send a "$/cancelRequest" to the client
+type NotifyWithContextFunc func(context contextpkg.Context, method string, params any) error
+type CallWithContextFunc func(context contextpkg.Context, method string, params any, result any) error
+
 type Context struct {
 	Method  string
 	Params  json.RawMessage
-	Notify  NotifyFunc
-	Call    CallFunc
-	Context contextpkg.Context // can be nil
+	Notify  NotifyFunc         // errors are logged
+	Call    CallFunc           // errors are logged
+	Context contextpkg.Context // can be nil; for requests it is cancelled by "$/cancelRequest"
+
+	NotifyWithContext NotifyWithContextFunc
+	CallWithContext   CallWithContextFunc
+
+	State *State // per connection; can be nil
+}
+
+// Derives from Context (if not nil) so that the notification would be abandoned if
+// the current request is cancelled.
+func (self *Context) NotifyWithTimeout(timeout time.Duration, method string, params any) error {
+	context, cancel := contextpkg.WithTimeout(self.getContext(), timeout)
+	defer cancel()
+	return self.NotifyWithContext(context, method, params)
+}
+
+// Derives from Context (if not nil) so that the call would be cancelled if the
+// current request is cancelled.
+func (self *Context) CallWithTimeout(timeout time.Duration, method string, params any, result any) error {
+	context, cancel := contextpkg.WithTimeout(self.getContext(), timeout)
+	defer cancel()
+	return self.CallWithContext(context, method, params, result)
+}
+
+func (self *Context) getContext() contextpkg.Context {
+	if self.Context != nil {
+		return self.Context
+	} else {
+		return contextpkg.Background()
+	}
 }
 
 type Handler interface {
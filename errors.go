@@ -0,0 +1,98 @@
+package glsp
+
+import (
+	"fmt"
+)
+
+// See: https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#errorCodes
+
+type ErrorCode = int64
+
+const (
+	// Defined by JSON-RPC
+	ErrorCodeParseError     ErrorCode = -32700
+	ErrorCodeInvalidRequest ErrorCode = -32600
+	ErrorCodeMethodNotFound ErrorCode = -32601
+	ErrorCodeInvalidParams  ErrorCode = -32602
+	ErrorCodeInternalError  ErrorCode = -32603
+
+	/**
+	 * Error code indicating that a server received a notification or
+	 * request before the server has received the `initialize` request.
+	 */
+	ErrorCodeServerNotInitialized ErrorCode = -32002
+	ErrorCodeUnknownErrorCode     ErrorCode = -32001
+
+	/**
+	 * A request failed but it was syntactically correct, e.g the
+	 * method name was known and the parameters were valid. The error
+	 * message should contain human readable information about why
+	 * the request failed.
+	 *
+	 * @since 3.17.0
+	 */
+	ErrorCodeRequestFailed ErrorCode = -32803
+
+	/**
+	 * The server cancelled the request. This error code should
+	 * only be used for requests that explicitly support being
+	 * server cancellable.
+	 *
+	 * @since 3.17.0
+	 */
+	ErrorCodeServerCancelled ErrorCode = -32802
+
+	/**
+	 * The server detected that the content of a document got
+	 * modified outside normal conditions. A server should
+	 * NOT send this error code if it detects a content change
+	 * in it unprocessed messages. The result even computed
+	 * on an older state might still be useful for the client.
+	 *
+	 * If a client decides that a result is not of any use anymore
+	 * the client should cancel the request.
+	 */
+	ErrorCodeContentModified ErrorCode = -32801
+
+	/**
+	 * The client has canceled a request and a server as detected
+	 * the cancel.
+	 */
+	ErrorCodeRequestCancelled ErrorCode = -32800
+)
+
+//
+// Error
+//
+
+// Handlers can return this error in order to control the code and data of the response error.
+// Other (untyped) errors will be reported as [ErrorCodeInternalError].
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Data    any // optional; must be JSON-serializable
+}
+
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+	}
+}
+
+func NewErrorf(code ErrorCode, format string, args ...any) *Error {
+	return NewError(code, fmt.Sprintf(format, args...))
+}
+
+func NewErrorWithData(code ErrorCode, message string, data any) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	}
+}
+
+// ([error] interface)
+func (self *Error) Error() string {
+	return self.Message
+}
@@ -0,0 +1,183 @@
+package glob
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// See: https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#pattern
+
+//
+// Glob
+//
+
+// Supports the LSP glob syntax:
+//
+//   - "*" to match zero or more characters in a path segment
+//   - "?" to match on one character in a path segment
+//   - "**" to match any number of path segments, including none
+//   - "{}" to group conditions (e.g. "**/*.{ts,js}")
+//   - "[]" to declare a range of characters to match in a path segment (e.g. "example.[0-9]")
+//   - "[!...]" to negate a range of characters to match in a path segment (e.g. "example.[!0-9]")
+//
+// Paths are always separated by "/".
+type Glob struct {
+	Pattern    string
+	IgnoreCase bool
+
+	regexp *regexp.Regexp
+}
+
+func Compile(pattern string, ignoreCase bool) (*Glob, error) {
+	if regexp_, err := toRegexp(pattern, ignoreCase); err == nil {
+		return &Glob{
+			Pattern:    pattern,
+			IgnoreCase: ignoreCase,
+			regexp:     regexp_,
+		}, nil
+	} else {
+		return nil, err
+	}
+}
+
+func MustCompile(pattern string, ignoreCase bool) *Glob {
+	if glob, err := Compile(pattern, ignoreCase); err == nil {
+		return glob
+	} else {
+		panic(err)
+	}
+}
+
+type cacheKey struct {
+	pattern    string
+	ignoreCase bool
+}
+
+var cache sync.Map
+
+// Like [Compile] but caches the result.
+func Get(pattern string, ignoreCase bool) (*Glob, error) {
+	key := cacheKey{pattern, ignoreCase}
+	if glob, ok := cache.Load(key); ok {
+		return glob.(*Glob), nil
+	}
+
+	if glob, err := Compile(pattern, ignoreCase); err == nil {
+		glob_, _ := cache.LoadOrStore(key, glob)
+		return glob_.(*Glob), nil
+	} else {
+		return nil, err
+	}
+}
+
+// Invalid patterns never match.
+func Match(pattern string, path string, ignoreCase bool) bool {
+	if glob, err := Get(pattern, ignoreCase); err == nil {
+		return glob.Match(path)
+	} else {
+		return false
+	}
+}
+
+func (self *Glob) Match(path string) bool {
+	return self.regexp.MatchString(path)
+}
+
+// ([fmt.Stringer] interface)
+func (self *Glob) String() string {
+	return self.Pattern
+}
+
+func toRegexp(pattern string, ignoreCase bool) (*regexp.Regexp, error) {
+	var builder strings.Builder
+	if ignoreCase {
+		builder.WriteString("(?i)")
+	}
+	builder.WriteRune('^')
+
+	groupDepth := 0
+	for index := 0; index < len(pattern); index++ {
+		c := pattern[index]
+		switch c {
+		case '*':
+			if (index+1 < len(pattern)) && (pattern[index+1] == '*') {
+				index++
+				if (index+1 < len(pattern)) && (pattern[index+1] == '/') {
+					// "**/" matches any number of segments, including none
+					index++
+					builder.WriteString("(?:.*/)?")
+				} else {
+					builder.WriteString(".*")
+				}
+			} else {
+				builder.WriteString("[^/]*")
+			}
+
+		case '?':
+			builder.WriteString("[^/]")
+
+		case '{':
+			groupDepth++
+			builder.WriteString("(?:")
+
+		case '}':
+			if groupDepth > 0 {
+				groupDepth--
+				builder.WriteRune(')')
+			} else {
+				builder.WriteString(`\}`)
+			}
+
+		case ',':
+			if groupDepth > 0 {
+				builder.WriteRune('|')
+			} else {
+				builder.WriteRune(',')
+			}
+
+		case '[':
+			if end := strings.IndexByte(pattern[index+1:], ']'); end > 0 {
+				class := pattern[index+1 : index+1+end]
+				index += end + 1
+
+				negate := class[0] == '!'
+				if negate {
+					class = class[1:]
+				}
+
+				builder.WriteRune('[')
+				if negate {
+					builder.WriteRune('^')
+				}
+				for _, r := range class {
+					if r == '/' {
+						// Ranges never match the separator
+						continue
+					}
+					if (r == '\\') || (r == '[') || (r == ']') || (r == '^') {
+						builder.WriteRune('\\')
+					}
+					builder.WriteRune(r)
+				}
+				if negate {
+					builder.WriteRune('/')
+				}
+				builder.WriteRune(']')
+			} else {
+				builder.WriteString(`\[`)
+			}
+
+		default:
+			builder.WriteString(regexp.QuoteMeta(pattern[index : index+1]))
+		}
+	}
+
+	if groupDepth != 0 {
+		return nil, fmt.Errorf("unclosed group in glob pattern: %s", pattern)
+	}
+
+	builder.WriteRune('$')
+	return regexp.Compile(builder.String())
+}
@@ -0,0 +1,83 @@
+package glob
+
+import (
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		ignoreCase bool
+		path       string
+		match      bool
+	}{
+		// "*" and "?"
+		{"*.go", false, "main.go", true},
+		{"*.go", false, "src/main.go", false},
+		{"?.go", false, "a.go", true},
+		{"?.go", false, "ab.go", false},
+		{"?.go", false, "/.go", false},
+
+		// "**"
+		{"**/*.go", false, "main.go", true},
+		{"**/*.go", false, "src/main.go", true},
+		{"**/*.go", false, "src/pkg/main.go", true},
+		{"**/*.go", false, "main.ts", false},
+		{"src/**/*.go", false, "src/main.go", true},
+		{"src/**/*.go", false, "src/a/b/main.go", true},
+		{"src/**/*.go", false, "other/main.go", false},
+		{"src/**", false, "src/a/b", true},
+		{"**", false, "a/b/c", true},
+
+		// "{}"
+		{"*.{ts,js}", false, "main.ts", true},
+		{"*.{ts,js}", false, "main.js", true},
+		{"*.{ts,js}", false, "main.go", false},
+		{"{a,{b,c}}.txt", false, "a.txt", true},
+		{"{a,{b,c}}.txt", false, "b.txt", true},
+		{"{a,{b,c}}.txt", false, "c.txt", true},
+		{"{a,{b,c}}.txt", false, "d.txt", false},
+		{"{a,{b,c}}.txt", false, "{b,c}.txt", false},
+		{"a,b", false, "a,b", true},
+
+		// "[]"
+		{"example.[0-9]", false, "example.5", true},
+		{"example.[0-9]", false, "example.a", false},
+		{"example.[!0-9]", false, "example.a", true},
+		{"example.[!0-9]", false, "example.5", false},
+		{"a[!b]c", false, "a/c", false},
+		{"a[/]c", false, "a/c", false},
+		{"[^a]", false, "^", true},
+		{"[^a]", false, "b", false},
+		{"a[", false, "a[", true},
+
+		// Special regexp characters are literal
+		{"a+b.(c)", false, "a+b.(c)", true},
+		{"a+b.(c)", false, "aab.(c)", false},
+
+		// IgnoreCase
+		{"**/*.GO", false, "src/main.go", false},
+		{"**/*.GO", true, "src/main.go", true},
+		{"SRC/{Main,Test}.go", true, "src/test.GO", true},
+		{"[A-Z].txt", true, "a.txt", true},
+		{"[!A-Z].txt", true, "a.txt", false},
+	}
+
+	for _, test := range tests {
+		if match := Match(test.pattern, test.path, test.ignoreCase); match != test.match {
+			t.Errorf("Match(%q, %q, %t) = %t, expected %t", test.pattern, test.path, test.ignoreCase, match, test.match)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, pattern := range []string{"{a,b", "{a,{b}"} {
+		if _, err := Compile(pattern, false); err == nil {
+			t.Errorf("Compile(%q) should fail", pattern)
+		}
+	}
+
+	if Match("{a,b", "a", false) {
+		t.Error("invalid pattern should never match")
+	}
+}
@@ -0,0 +1,101 @@
+package glsp
+
+import (
+	"time"
+
+	"github.com/tliron/commonlog"
+)
+
+//
+// HandlerFunc
+//
+
+type HandlerFunc func(context *Context) (result any, validMethod bool, validParams bool, err error)
+
+// ([Handler] interface)
+func (self HandlerFunc) Handle(context *Context) (result any, validMethod bool, validParams bool, err error) {
+	return self(context)
+}
+
+//
+// Middleware
+//
+
+type Middleware func(next Handler) Handler
+
+// The first middleware will be the outermost, i.e. the first to handle the message.
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for index := len(middlewares) - 1; index >= 0; index-- {
+		handler = middlewares[index](handler)
+	}
+	return handler
+}
+
+// Logs every message and its error, if there is one.
+func NewLoggingMiddleware(log commonlog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(context *Context) (any, bool, bool, error) {
+			log.Debugf("handling: %s", context.Method)
+			result, validMethod, validParams, err := next.Handle(context)
+			if !validMethod {
+				log.Warningf("method not supported: %s", context.Method)
+			} else if err != nil {
+				log.Errorf("error handling %s: %s", context.Method, err.Error())
+			}
+			return result, validMethod, validParams, err
+		})
+	}
+}
+
+type LatencyFunc func(method string, duration time.Duration)
+
+// Measures how long it took to handle every message.
+func NewLatencyMiddleware(latency LatencyFunc) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(context *Context) (any, bool, bool, error) {
+			start := time.Now()
+			defer func() {
+				latency(context.Method, time.Since(start))
+			}()
+			return next.Handle(context)
+		})
+	}
+}
+
+// Only the listed methods will be handled. Others will be reported as not supported.
+func NewAllowMethodsMiddleware(methods ...string) Middleware {
+	allowed := toMethodSet(methods)
+	return func(next Handler) Handler {
+		return HandlerFunc(func(context *Context) (any, bool, bool, error) {
+			if _, ok := allowed[context.Method]; ok {
+				return next.Handle(context)
+			} else {
+				return nil, false, false, nil
+			}
+		})
+	}
+}
+
+// The listed methods will be reported as not supported. Others will be handled.
+func NewDenyMethodsMiddleware(methods ...string) Middleware {
+	denied := toMethodSet(methods)
+	return func(next Handler) Handler {
+		return HandlerFunc(func(context *Context) (any, bool, bool, error) {
+			if _, ok := denied[context.Method]; ok {
+				return nil, false, false, nil
+			} else {
+				return next.Handle(context)
+			}
+		})
+	}
+}
+
+// Utils
+
+func toMethodSet(methods []string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, method := range methods {
+		set[method] = struct{}{}
+	}
+	return set
+}
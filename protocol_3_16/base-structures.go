@@ -430,16 +430,28 @@ func (self *TextDocumentEdit) UnmarshalJSON(data []byte) error {
 		self.TextDocument = value.TextDocument
 
 		for _, edit := range value.Edits {
-			var value TextEdit
-			if err = json.Unmarshal(edit, &value); err == nil {
-				self.Edits = append(self.Edits, value)
-			} else {
+			// AnnotatedTextEdit is distinguished by its annotation ID
+			var annotation struct {
+				AnnotationID *ChangeAnnotationIdentifier `json:"annotationId"`
+			}
+			if err = json.Unmarshal(edit, &annotation); err != nil {
+				return err
+			}
+
+			if annotation.AnnotationID != nil {
 				var value AnnotatedTextEdit
 				if err = json.Unmarshal(edit, &value); err == nil {
 					self.Edits = append(self.Edits, value)
 				} else {
 					return err
 				}
+			} else {
+				var value TextEdit
+				if err = json.Unmarshal(edit, &value); err == nil {
+					self.Edits = append(self.Edits, value)
+				} else {
+					return err
+				}
 			}
 		}
 
@@ -633,25 +645,45 @@ func (self *WorkspaceEdit) UnmarshalJSON(data []byte) error {
 		self.ChangeAnnotations = value.ChangeAnnotations
 
 		for _, documentChange := range value.DocumentChanges {
-			var value TextDocumentEdit
-			if err = json.Unmarshal(documentChange, &value); err == nil {
-				self.DocumentChanges = append(self.DocumentChanges, value)
-			} else {
+			// Resource operations are distinguished by their kind (TextDocumentEdit has none)
+			var kind struct {
+				Kind ResourceOperationKind `json:"kind"`
+			}
+			if err = json.Unmarshal(documentChange, &kind); err != nil {
+				return err
+			}
+
+			switch kind.Kind {
+			case ResourceOperationKindCreate:
 				var value CreateFile
 				if err = json.Unmarshal(documentChange, &value); err == nil {
 					self.DocumentChanges = append(self.DocumentChanges, value)
 				} else {
-					var value RenameFile
-					if err = json.Unmarshal(documentChange, &value); err == nil {
-						self.DocumentChanges = append(self.DocumentChanges, value)
-					} else {
-						var value DeleteFile
-						if err = json.Unmarshal(documentChange, &value); err == nil {
-							self.DocumentChanges = append(self.DocumentChanges, value)
-						} else {
-							return err
-						}
-					}
+					return err
+				}
+
+			case ResourceOperationKindRename:
+				var value RenameFile
+				if err = json.Unmarshal(documentChange, &value); err == nil {
+					self.DocumentChanges = append(self.DocumentChanges, value)
+				} else {
+					return err
+				}
+
+			case ResourceOperationKindDelete:
+				var value DeleteFile
+				if err = json.Unmarshal(documentChange, &value); err == nil {
+					self.DocumentChanges = append(self.DocumentChanges, value)
+				} else {
+					return err
+				}
+
+			default:
+				var value TextDocumentEdit
+				if err = json.Unmarshal(documentChange, &value); err == nil {
+					self.DocumentChanges = append(self.DocumentChanges, value)
+				} else {
+					return err
 				}
 			}
 		}
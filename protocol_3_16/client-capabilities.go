@@ -0,0 +1,285 @@
+package protocol
+
+import (
+	"github.com/tliron/glsp"
+)
+
+type initializeParamsKey struct{}
+
+// Keeps the params for the lifetime of the connection. This is called automatically by
+// [Handler] for "initialize".
+func SetInitializeParams(context *glsp.Context, params *InitializeParams) {
+	if context.State != nil {
+		context.State.Set(initializeParamsKey{}, params)
+	}
+}
+
+// Returns the params of the connection's "initialize" request, or nil if it has not been
+// received yet.
+func GetInitializeParams(context *glsp.Context) *InitializeParams {
+	if context.State != nil {
+		if params, ok := context.State.Get(initializeParamsKey{}); ok {
+			return params.(*InitializeParams)
+		}
+	}
+	return nil
+}
+
+// Returns the client capabilities of the connection, or nil if "initialize" has not been
+// received yet. Note that all the Supports methods can be safely called on nil.
+func GetClientCapabilities(context *glsp.Context) *ClientCapabilities {
+	if params := GetInitializeParams(context); params != nil {
+		return &params.Capabilities
+	}
+	return nil
+}
+
+//
+// ClientCapabilities
+//
+
+func (self *ClientCapabilities) SupportsHoverMarkdown() bool {
+	if (self != nil) && (self.TextDocument != nil) && (self.TextDocument.Hover != nil) {
+		return containsMarkdown(self.TextDocument.Hover.ContentFormat)
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsCompletionSnippets() bool {
+	if (self != nil) && (self.TextDocument != nil) && (self.TextDocument.Completion != nil) && (self.TextDocument.Completion.CompletionItem != nil) {
+		return isTrue(self.TextDocument.Completion.CompletionItem.SnippetSupport)
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsCompletionDocumentationMarkdown() bool {
+	if (self != nil) && (self.TextDocument != nil) && (self.TextDocument.Completion != nil) && (self.TextDocument.Completion.CompletionItem != nil) {
+		return containsMarkdown(self.TextDocument.Completion.CompletionItem.DocumentationFormat)
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsCompletionInsertReplace() bool {
+	if (self != nil) && (self.TextDocument != nil) && (self.TextDocument.Completion != nil) && (self.TextDocument.Completion.CompletionItem != nil) {
+		return isTrue(self.TextDocument.Completion.CompletionItem.InsertReplaceSupport)
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsCompletionContext() bool {
+	if (self != nil) && (self.TextDocument != nil) && (self.TextDocument.Completion != nil) {
+		return isTrue(self.TextDocument.Completion.ContextSupport)
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsSignatureHelpDocumentationMarkdown() bool {
+	if (self != nil) && (self.TextDocument != nil) && (self.TextDocument.SignatureHelp != nil) && (self.TextDocument.SignatureHelp.SignatureInformation != nil) {
+		return containsMarkdown(self.TextDocument.SignatureHelp.SignatureInformation.DocumentationFormat)
+	}
+	return false
+}
+
+// Whether the client accepts []LocationLink as the result of the method, which can be
+// "textDocument/declaration", "textDocument/definition", "textDocument/typeDefinition",
+// or "textDocument/implementation".
+func (self *ClientCapabilities) SupportsLocationLinks(method Method) bool {
+	if (self == nil) || (self.TextDocument == nil) {
+		return false
+	}
+
+	switch method {
+	case MethodTextDocumentDeclaration:
+		return (self.TextDocument.Declaration != nil) && isTrue(self.TextDocument.Declaration.LinkSupport)
+	case MethodTextDocumentDefinition:
+		return (self.TextDocument.Definition != nil) && isTrue(self.TextDocument.Definition.LinkSupport)
+	case MethodTextDocumentTypeDefinition:
+		return (self.TextDocument.TypeDefinition != nil) && isTrue(self.TextDocument.TypeDefinition.LinkSupport)
+	case MethodTextDocumentImplementation:
+		return (self.TextDocument.Implementation != nil) && isTrue(self.TextDocument.Implementation.LinkSupport)
+	default:
+		return false
+	}
+}
+
+// Whether the client accepts []DocumentSymbol (rather than []SymbolInformation) as the
+// result of "textDocument/documentSymbol".
+func (self *ClientCapabilities) SupportsHierarchicalDocumentSymbols() bool {
+	if (self != nil) && (self.TextDocument != nil) && (self.TextDocument.DocumentSymbol != nil) {
+		return isTrue(self.TextDocument.DocumentSymbol.HierarchicalDocumentSymbolSupport)
+	}
+	return false
+}
+
+// Whether the client accepts []CodeAction (rather than []Command) as the result of
+// "textDocument/codeAction".
+func (self *ClientCapabilities) SupportsCodeActionLiterals() bool {
+	if (self != nil) && (self.TextDocument != nil) && (self.TextDocument.CodeAction != nil) {
+		return self.TextDocument.CodeAction.CodeActionLiteralSupport != nil
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsPrepareRename() bool {
+	if (self != nil) && (self.TextDocument != nil) && (self.TextDocument.Rename != nil) {
+		return isTrue(self.TextDocument.Rename.PrepareSupport)
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsPublishDiagnosticsVersion() bool {
+	if (self != nil) && (self.TextDocument != nil) && (self.TextDocument.PublishDiagnostics != nil) {
+		return isTrue(self.TextDocument.PublishDiagnostics.VersionSupport)
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsApplyEdit() bool {
+	if (self != nil) && (self.Workspace != nil) {
+		return isTrue(self.Workspace.ApplyEdit)
+	}
+	return false
+}
+
+// Whether the client accepts DocumentChanges (rather than Changes) in a [WorkspaceEdit].
+func (self *ClientCapabilities) SupportsWorkspaceEditDocumentChanges() bool {
+	if (self != nil) && (self.Workspace != nil) && (self.Workspace.WorkspaceEdit != nil) {
+		return isTrue(self.Workspace.WorkspaceEdit.DocumentChanges)
+	}
+	return false
+}
+
+// Whether the client accepts the kind of resource operation (create, rename, or delete) in a
+// [WorkspaceEdit].
+func (self *ClientCapabilities) SupportsResourceOperation(kind ResourceOperationKind) bool {
+	if (self != nil) && (self.Workspace != nil) && (self.Workspace.WorkspaceEdit != nil) {
+		for _, kind_ := range self.Workspace.WorkspaceEdit.ResourceOperations {
+			if kind == kind_ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsWorkspaceFolders() bool {
+	if (self != nil) && (self.Workspace != nil) {
+		return isTrue(self.Workspace.WorkspaceFolders)
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsConfiguration() bool {
+	if (self != nil) && (self.Workspace != nil) {
+		return isTrue(self.Workspace.Configuration)
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsWorkDoneProgress() bool {
+	if (self != nil) && (self.Window != nil) {
+		return isTrue(self.Window.WorkDoneProgress)
+	}
+	return false
+}
+
+func (self *ClientCapabilities) SupportsShowDocument() bool {
+	if (self != nil) && (self.Window != nil) && (self.Window.ShowDocument != nil) {
+		return self.Window.ShowDocument.Support
+	}
+	return false
+}
+
+// Whether the client supports "client/registerCapability" for the method.
+//
+// Note that all the "workspace/didCreateFiles" etc. file operation methods share a single
+// capability, as do all the "textDocument/semanticTokens" methods and all the
+// text document synchronization methods.
+func (self *ClientCapabilities) SupportsDynamicRegistration(method Method) bool {
+	if self == nil {
+		return false
+	}
+
+	if workspace := self.Workspace; workspace != nil {
+		switch method {
+		case MethodWorkspaceDidChangeConfiguration:
+			return (workspace.DidChangeConfiguration != nil) && isTrue(workspace.DidChangeConfiguration.DynamicRegistration)
+		case MethodWorkspaceDidChangeWatchedFiles:
+			return (workspace.DidChangeWatchedFiles != nil) && isTrue(workspace.DidChangeWatchedFiles.DynamicRegistration)
+		case MethodWorkspaceSymbol:
+			return (workspace.Symbol != nil) && isTrue(workspace.Symbol.DynamicRegistration)
+		case MethodWorkspaceExecuteCommand:
+			return (workspace.ExecuteCommand != nil) && isTrue(workspace.ExecuteCommand.DynamicRegistration)
+		case MethodWorkspaceWillCreateFiles, MethodWorkspaceDidCreateFiles, MethodWorkspaceWillRenameFiles, MethodWorkspaceDidRenameFiles, MethodWorkspaceWillDeleteFiles, MethodWorkspaceDidDeleteFiles:
+			return (workspace.FileOperations != nil) && isTrue(workspace.FileOperations.DynamicRegistration)
+		}
+	}
+
+	if textDocument := self.TextDocument; textDocument != nil {
+		switch method {
+		case MethodTextDocumentDidOpen, MethodTextDocumentDidChange, MethodTextDocumentWillSave, MethodTextDocumentWillSaveWaitUntil, MethodTextDocumentDidSave, MethodTextDocumentDidClose:
+			return (textDocument.Synchronization != nil) && isTrue(textDocument.Synchronization.DynamicRegistration)
+		case MethodTextDocumentCompletion:
+			return (textDocument.Completion != nil) && isTrue(textDocument.Completion.DynamicRegistration)
+		case MethodTextDocumentHover:
+			return (textDocument.Hover != nil) && isTrue(textDocument.Hover.DynamicRegistration)
+		case MethodTextDocumentSignatureHelp:
+			return (textDocument.SignatureHelp != nil) && isTrue(textDocument.SignatureHelp.DynamicRegistration)
+		case MethodTextDocumentDeclaration:
+			return (textDocument.Declaration != nil) && isTrue(textDocument.Declaration.DynamicRegistration)
+		case MethodTextDocumentDefinition:
+			return (textDocument.Definition != nil) && isTrue(textDocument.Definition.DynamicRegistration)
+		case MethodTextDocumentTypeDefinition:
+			return (textDocument.TypeDefinition != nil) && isTrue(textDocument.TypeDefinition.DynamicRegistration)
+		case MethodTextDocumentImplementation:
+			return (textDocument.Implementation != nil) && isTrue(textDocument.Implementation.DynamicRegistration)
+		case MethodTextDocumentReferences:
+			return (textDocument.References != nil) && isTrue(textDocument.References.DynamicRegistration)
+		case MethodTextDocumentDocumentHighlight:
+			return (textDocument.DocumentHighlight != nil) && isTrue(textDocument.DocumentHighlight.DynamicRegistration)
+		case MethodTextDocumentDocumentSymbol:
+			return (textDocument.DocumentSymbol != nil) && isTrue(textDocument.DocumentSymbol.DynamicRegistration)
+		case MethodTextDocumentCodeAction:
+			return (textDocument.CodeAction != nil) && isTrue(textDocument.CodeAction.DynamicRegistration)
+		case MethodTextDocumentCodeLens:
+			return (textDocument.CodeLens != nil) && isTrue(textDocument.CodeLens.DynamicRegistration)
+		case MethodTextDocumentDocumentLink:
+			return (textDocument.DocumentLink != nil) && isTrue(textDocument.DocumentLink.DynamicRegistration)
+		case MethodTextDocumentColor:
+			return (textDocument.ColorProvider != nil) && isTrue(textDocument.ColorProvider.DynamicRegistration)
+		case MethodTextDocumentFormatting:
+			return (textDocument.Formatting != nil) && isTrue(textDocument.Formatting.DynamicRegistration)
+		case MethodTextDocumentRangeFormatting:
+			return (textDocument.RangeFormatting != nil) && isTrue(textDocument.RangeFormatting.DynamicRegistration)
+		case MethodTextDocumentOnTypeFormatting:
+			return (textDocument.OnTypeFormatting != nil) && isTrue(textDocument.OnTypeFormatting.DynamicRegistration)
+		case MethodTextDocumentRename:
+			return (textDocument.Rename != nil) && isTrue(textDocument.Rename.DynamicRegistration)
+		case MethodTextDocumentFoldingRange:
+			return (textDocument.FoldingRange != nil) && isTrue(textDocument.FoldingRange.DynamicRegistration)
+		case MethodTextDocumentSelectionRange:
+			return (textDocument.SelectionRange != nil) && isTrue(textDocument.SelectionRange.DynamicRegistration)
+		case MethodTextDocumentLinkedEditingRange:
+			return (textDocument.LinkedEditingRange != nil) && isTrue(textDocument.LinkedEditingRange.DynamicRegistration)
+		case MethodTextDocumentPrepareCallHierarchy:
+			return (textDocument.CallHierarchy != nil) && isTrue(textDocument.CallHierarchy.DynamicRegistration)
+		case MethodTextDocumentSemanticTokensFull, MethodTextDocumentSemanticTokensFullDelta, MethodTextDocumentSemanticTokensRange:
+			return (textDocument.SemanticTokens != nil) && isTrue(textDocument.SemanticTokens.DynamicRegistration)
+		case MethodTextDocumentMoniker:
+			return (textDocument.Moniker != nil) && isTrue(textDocument.Moniker.DynamicRegistration)
+		}
+	}
+
+	return false
+}
+
+// Utils
+
+func containsMarkdown(kinds []MarkupKind) bool {
+	for _, kind := range kinds {
+		if kind == MarkupKindMarkdown {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package protocol
+
+import (
+	contextpkg "context"
+
+	"github.com/tliron/glsp"
+)
+
+//
+// Client
+//
+
+// Typed access to the requests and notifications that the server can send to the client
+type Client struct {
+	context *glsp.Context
+}
+
+func NewClient(context *glsp.Context) *Client {
+	return &Client{context}
+}
+
+// Window
+
+func (self *Client) ShowMessage(context contextpkg.Context, params *ShowMessageParams) error {
+	return self.context.NotifyWithContext(context, ServerWindowShowMessage, params)
+}
+
+// Result can be nil if no action was selected
+func (self *Client) ShowMessageRequest(context contextpkg.Context, params *ShowMessageRequestParams) (*MessageActionItem, error) {
+	var result *MessageActionItem
+	if err := self.context.CallWithContext(context, ServerWindowShowMessageRequest, params, &result); err == nil {
+		return result, nil
+	} else {
+		return nil, err
+	}
+}
+
+func (self *Client) ShowDocument(context contextpkg.Context, params *ShowDocumentParams) (*ShowDocumentResult, error) {
+	var result ShowDocumentResult
+	if err := self.context.CallWithContext(context, ServerWindowShowDocument, params, &result); err == nil {
+		return &result, nil
+	} else {
+		return nil, err
+	}
+}
+
+func (self *Client) LogMessage(context contextpkg.Context, params *LogMessageParams) error {
+	return self.context.NotifyWithContext(context, ServerWindowLogMessage, params)
+}
+
+func (self *Client) WorkDoneProgressCreate(context contextpkg.Context, params *WorkDoneProgressCreateParams) error {
+	return self.context.CallWithContext(context, ServerWindowWorkDoneProgressCreate, params, nil)
+}
+
+// Base Protocol
+
+func (self *Client) Progress(context contextpkg.Context, params *ProgressParams) error {
+	return self.context.NotifyWithContext(context, MethodProgress, params)
+}
+
+// Should only be sent if the trace value set by the client (see [GetTraceValue]) is not "off"
+func (self *Client) LogTrace(context contextpkg.Context, params *LogTraceParams) error {
+	return self.context.NotifyWithContext(context, MethodLogTrace, params)
+}
+
+// Workspace
+
+// Result can be nil if only a single file is open in the tool
+func (self *Client) WorkspaceFolders(context contextpkg.Context) ([]WorkspaceFolder, error) {
+	var result []WorkspaceFolder
+	if err := self.context.CallWithContext(context, ServerWorkspaceWorkspaceFolders, nil, &result); err == nil {
+		return result, nil
+	} else {
+		return nil, err
+	}
+}
+
+// The result has an entry for each of params.Items, in order
+func (self *Client) Configuration(context contextpkg.Context, params *ConfigurationParams) ([]any, error) {
+	var result []any
+	if err := self.context.CallWithContext(context, ServerWorkspaceConfiguration, params, &result); err == nil {
+		return result, nil
+	} else {
+		return nil, err
+	}
+}
+
+func (self *Client) ApplyEdit(context contextpkg.Context, params *ApplyWorkspaceEditParams) (*ApplyWorkspaceEditResponse, error) {
+	var result ApplyWorkspaceEditResponse
+	if err := self.context.CallWithContext(context, ServerWorkspaceApplyEdit, params, &result); err == nil {
+		return &result, nil
+	} else {
+		return nil, err
+	}
+}
+
+func (self *Client) CodeLensRefresh(context contextpkg.Context) error {
+	return self.context.CallWithContext(context, ServerWorkspaceCodeLensRefresh, nil, nil)
+}
+
+func (self *Client) SemanticTokensRefresh(context contextpkg.Context) error {
+	return self.context.CallWithContext(context, MethodWorkspaceSemanticTokensRefresh, nil, nil)
+}
+
+// Client
+
+func (self *Client) RegisterCapability(context contextpkg.Context, params *RegistrationParams) error {
+	return self.context.CallWithContext(context, ServerClientRegisterCapability, params, nil)
+}
+
+func (self *Client) UnregisterCapability(context contextpkg.Context, params *UnregistrationParams) error {
+	return self.context.CallWithContext(context, ServerClientUnregisterCapability, params, nil)
+}
+
+// Diagnostics
+
+func (self *Client) PublishDiagnostics(context contextpkg.Context, params *PublishDiagnosticsParams) error {
+	return self.context.NotifyWithContext(context, ServerTextDocumentPublishDiagnostics, params)
+}
+
+// Telemetry
+
+func (self *Client) TelemetryEvent(context contextpkg.Context, data any) error {
+	return self.context.NotifyWithContext(context, ServerTelemetryEvent, data)
+}
@@ -0,0 +1,97 @@
+package protocol
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/glsp"
+	"github.com/tliron/glsp/server"
+)
+
+const testTimeout = 5 * time.Second
+
+// Serves the handler and returns the client's end of the connection. Requests from the server
+// are answered by the respond function.
+func connect(t *testing.T, handler glsp.Handler, respond func(method string, params json.RawMessage) any) *jsonrpc2.Conn {
+	serverStream, clientStream := net.Pipe()
+
+	server_ := server.NewServer(handler, "test", false)
+	go server_.ServeStream(serverStream, nil)
+
+	client := jsonrpc2.NewConn(contextpkg.Background(), jsonrpc2.NewBufferedStream(clientStream, jsonrpc2.VSCodeObjectCodec{}), jsonrpc2.HandlerWithError(func(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+		var params json.RawMessage
+		if request.Params != nil {
+			params = *request.Params
+		}
+		return respond(request.Method, params), nil
+	}))
+
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	return client
+}
+
+// Sends "initialize" with the capabilities and then "initialized".
+func initialize(t *testing.T, client *jsonrpc2.Conn, capabilities ClientCapabilities) {
+	context, cancel := contextpkg.WithTimeout(contextpkg.Background(), testTimeout)
+	defer cancel()
+
+	if err := client.Call(context, string(MethodInitialize), &InitializeParams{Capabilities: capabilities}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Notify(context, string(MethodInitialized), &InitializedParams{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigurationFromInitialized(t *testing.T) {
+	results := make(chan []any, 1)
+	errs := make(chan error, 1)
+
+	handler := Handler{
+		Initialize: func(context *glsp.Context, params *InitializeParams) (any, error) {
+			return InitializeResult{}, nil
+		},
+		Initialized: func(context *glsp.Context, params *InitializedParams) error {
+			context_, cancel := contextpkg.WithTimeout(contextpkg.Background(), testTimeout)
+			defer cancel()
+
+			section := "test"
+			if result, err := NewClient(context).Configuration(context_, &ConfigurationParams{
+				Items: []ConfigurationItem{{Section: &section}},
+			}); err == nil {
+				results <- result
+			} else {
+				errs <- err
+			}
+			return nil
+		},
+	}
+
+	client := connect(t, &handler, func(method string, params json.RawMessage) any {
+		if method == string(ServerWorkspaceConfiguration) {
+			return []any{map[string]any{"enabled": true}}
+		}
+		return nil
+	})
+
+	initialize(t, client, ClientCapabilities{})
+
+	select {
+	case result := <-results:
+		if (len(result) != 1) || (result[0].(map[string]any)["enabled"] != true) {
+			t.Errorf("unexpected configuration: %v", result)
+		}
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(testTimeout):
+		t.Fatal("timed out")
+	}
+}
@@ -0,0 +1,132 @@
+package protocol
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+//
+// CompletionFilter
+//
+
+// Filters, ranks, and truncates completion items on the server, so that large lists need
+// not be sent in their entirety to the client. Matching and scoring is done with
+// [FuzzyScore] against each item's FilterText (or Label if there is none).
+type CompletionFilter struct {
+	// If more items match then the list is truncated and marked as incomplete, so that the
+	// client will ask again as the user continues typing. Zero means no limit.
+	MaxItems int
+
+	// The trigger characters that the server registered (see [CompletionOptions]). If not
+	// empty then completions triggered by other characters will return an empty list.
+	TriggerCharacters []string
+}
+
+func NewCompletionFilter(maxItems int, triggerCharacters ...string) *CompletionFilter {
+	return &CompletionFilter{
+		MaxItems:          maxItems,
+		TriggerCharacters: triggerCharacters,
+	}
+}
+
+// Returns the items that match the prefix (the text typed so far, see [CompletionPrefix]),
+// best first. SortText is set on all returned items to preserve that order on the client.
+//
+// Ties are broken by the items' original SortText (or Label), so servers can still express
+// a preference.
+func (self *CompletionFilter) Filter(params *CompletionParams, prefix string, items []CompletionItem) *CompletionList {
+	// Note that re-triggering for incomplete completions needs no special handling: we
+	// simply filter again with the longer prefix
+	if (params != nil) && (params.Context != nil) && (params.Context.TriggerKind == CompletionTriggerKindTriggerCharacter) {
+		if (params.Context.TriggerCharacter != nil) && !self.isTriggerCharacter(*params.Context.TriggerCharacter) {
+			return &CompletionList{Items: []CompletionItem{}}
+		}
+	}
+
+	type scoredItem struct {
+		item    CompletionItem
+		score   int
+		sortKey string
+	}
+
+	scoredItems := make([]scoredItem, 0, len(items))
+	for _, item := range items {
+		filterText := item.Label
+		if item.FilterText != nil {
+			filterText = *item.FilterText
+		}
+
+		if score, ok := FuzzyScore(prefix, filterText); ok {
+			sortKey := item.Label
+			if item.SortText != nil {
+				sortKey = *item.SortText
+			}
+			scoredItems = append(scoredItems, scoredItem{item, score, sortKey})
+		}
+	}
+
+	sort.SliceStable(scoredItems, func(i int, j int) bool {
+		a, b := scoredItems[i], scoredItems[j]
+		if a.score != b.score {
+			return a.score > b.score
+		} else if a.sortKey != b.sortKey {
+			return a.sortKey < b.sortKey
+		} else {
+			return a.item.Label < b.item.Label
+		}
+	})
+
+	list := CompletionList{Items: make([]CompletionItem, 0, len(scoredItems))}
+	if (self.MaxItems > 0) && (len(scoredItems) > self.MaxItems) {
+		scoredItems = scoredItems[:self.MaxItems]
+		list.IsIncomplete = true
+	}
+
+	// Zero-padded so that lexical order is numerical order
+	format := "%0" + strconv.Itoa(len(strconv.Itoa(len(scoredItems)))) + "d"
+	for index, scoredItem := range scoredItems {
+		sortText := fmt.Sprintf(format, index)
+		scoredItem.item.SortText = &sortText
+		list.Items = append(list.Items, scoredItem.item)
+	}
+
+	return &list
+}
+
+func (self *CompletionFilter) isTriggerCharacter(character string) bool {
+	if len(self.TriggerCharacters) == 0 {
+		return true
+	}
+
+	for _, triggerCharacter := range self.TriggerCharacters {
+		if triggerCharacter == character {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the word immediately before the position on its line, which is the text that the
+// user has typed so far. Word characters are letters, digits, and "_".
+//
+// Note that trigger characters are not word characters, so right after a trigger character
+// the prefix is empty.
+func CompletionPrefix(index *LineIndex, position Position) string {
+	end := index.IndexOf(position)
+	lineStart, _ := index.lineBounds(int(position.Line))
+
+	start := end
+	for start > lineStart {
+		r, width := utf8.DecodeLastRuneInString(index.content[lineStart:start])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && (r != '_') {
+			break
+		}
+		start -= width
+	}
+
+	return index.content[start:end]
+}
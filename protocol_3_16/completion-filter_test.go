@@ -0,0 +1,136 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func completionLabels(list *CompletionList) []string {
+	labels := make([]string, len(list.Items))
+	for index, item := range list.Items {
+		labels[index] = item.Label
+	}
+	return labels
+}
+
+func TestCompletionFilter(t *testing.T) {
+	items := []CompletionItem{
+		{Label: "iconv"},
+		{Label: "Container"},
+		{Label: "console"},
+		{Label: "const"},
+		{Label: "print"},
+	}
+
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		// Equal scores for "console" and "const" are ordered by label
+		{"con", []string{"console", "const", "Container"}},
+		{"Con", []string{"Container", "console", "const"}},
+		{"const", []string{"const"}},
+		{"pr", []string{"print"}},
+		{"x", []string{}},
+		// Labels are ordered bytewise
+		{"", []string{"Container", "console", "const", "iconv", "print"}},
+	}
+
+	filter := NewCompletionFilter(0)
+	for _, test := range tests {
+		list := filter.Filter(nil, test.prefix, items)
+		if labels := completionLabels(list); !reflect.DeepEqual(labels, test.expected) {
+			t.Errorf("%q: got %v, expected %v", test.prefix, labels, test.expected)
+		}
+		if list.IsIncomplete {
+			t.Errorf("%q: incomplete", test.prefix)
+		}
+
+		// SortText preserves the order on the client
+		for index := 1; index < len(list.Items); index++ {
+			if *list.Items[index-1].SortText >= *list.Items[index].SortText {
+				t.Errorf("%q: SortText out of order: %q, %q", test.prefix, *list.Items[index-1].SortText, *list.Items[index].SortText)
+			}
+		}
+	}
+}
+
+func TestCompletionFilterText(t *testing.T) {
+	filterText := "println"
+	sortText := "0"
+	items := []CompletionItem{
+		{Label: "fmt.Println", FilterText: &filterText},
+		{Label: "printf"},
+		{Label: "print", SortText: &sortText},
+	}
+
+	// "print" and "printf" score equally, but "print" has a lower SortText
+	list := NewCompletionFilter(0).Filter(nil, "pri", items)
+	if labels, expected := completionLabels(list), []string{"print", "fmt.Println", "printf"}; !reflect.DeepEqual(labels, expected) {
+		t.Errorf("got %v, expected %v", labels, expected)
+	}
+}
+
+func TestCompletionFilterMaxItems(t *testing.T) {
+	var items []CompletionItem
+	for _, label := range []string{"a1", "a2", "a3", "a4", "a5", "a6", "a7", "a8", "a9", "a10", "a11", "a12"} {
+		items = append(items, CompletionItem{Label: label})
+	}
+
+	list := NewCompletionFilter(5).Filter(nil, "a", items)
+	if !list.IsIncomplete || (len(list.Items) != 5) {
+		t.Errorf("got %d items (incomplete %t), expected 5 (incomplete)", len(list.Items), list.IsIncomplete)
+	}
+	if sortText := *list.Items[0].SortText; sortText != "0" {
+		t.Errorf("got SortText %q, expected \"0\"", sortText)
+	}
+
+	list = NewCompletionFilter(20).Filter(nil, "a", items)
+	if list.IsIncomplete || (len(list.Items) != 12) {
+		t.Errorf("got %d items (incomplete %t), expected 12 (complete)", len(list.Items), list.IsIncomplete)
+	}
+	if sortText := *list.Items[0].SortText; sortText != "00" {
+		t.Errorf("got SortText %q, expected \"00\"", sortText)
+	}
+}
+
+func TestCompletionFilterTriggerCharacter(t *testing.T) {
+	items := []CompletionItem{{Label: "field"}}
+	filter := NewCompletionFilter(0, ".")
+
+	for _, test := range []struct {
+		character string
+		count     int
+	}{
+		{".", 1},
+		{":", 0},
+	} {
+		character := test.character
+		params := CompletionParams{Context: &CompletionContext{TriggerKind: CompletionTriggerKindTriggerCharacter, TriggerCharacter: &character}}
+		if list := filter.Filter(&params, "", items); len(list.Items) != test.count {
+			t.Errorf("%q: got %d items, expected %d", test.character, len(list.Items), test.count)
+		}
+	}
+}
+
+func TestCompletionPrefix(t *testing.T) {
+	index := NewLineIndex("fmt.Pri\n  ünï_2 x\n")
+
+	tests := []struct {
+		position Position
+		prefix   string
+	}{
+		{Position{Line: 0, Character: 7}, "Pri"},
+		{Position{Line: 0, Character: 4}, ""},
+		{Position{Line: 0, Character: 3}, "fmt"},
+		{Position{Line: 1, Character: 7}, "ünï_2"},
+		{Position{Line: 1, Character: 8}, ""},
+		{Position{Line: 1, Character: 0}, ""},
+	}
+
+	for _, test := range tests {
+		if prefix := CompletionPrefix(index, test.position); prefix != test.prefix {
+			t.Errorf("%v: got %q, expected %q", test.position, prefix, test.prefix)
+		}
+	}
+}
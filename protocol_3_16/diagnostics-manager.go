@@ -0,0 +1,251 @@
+package protocol
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tliron/glsp"
+)
+
+var DefaultDiagnosticsDelay = 200 * time.Millisecond
+
+//
+// DiagnosticsManager
+//
+
+// Collects diagnostics per document from several sources (analyzers) and publishes
+// them via "textDocument/publishDiagnostics".
+//
+// Publishing is debounced: every submission for a document postpones its publication
+// by Delay, so that rapid changes result in a single notification.
+type DiagnosticsManager struct {
+	Notify glsp.NotifyFunc
+	Delay  time.Duration
+
+	// If set, diagnostics will be stripped of properties that the client does not support
+	Capabilities *PublishDiagnosticsClientCapabilities
+
+	documents   map[DocumentUri]*documentDiagnostics
+	lock        sync.Mutex
+	publishLock sync.Mutex // held while deciding what to publish and publishing it, so that publications are never reordered
+}
+
+type documentDiagnostics struct {
+	version *Integer // latest submitted
+	sources map[string]*sourceDiagnostics
+	timer   *time.Timer
+}
+
+type sourceDiagnostics struct {
+	version     *Integer
+	diagnostics []Diagnostic
+}
+
+func NewDiagnosticsManager(notify glsp.NotifyFunc) *DiagnosticsManager {
+	return &DiagnosticsManager{
+		Notify:    notify,
+		Delay:     DefaultDiagnosticsDelay,
+		documents: make(map[DocumentUri]*documentDiagnostics),
+	}
+}
+
+// Clears a document's diagnostics when it is closed and postpones pending publications
+// when it is changed.
+func (self *DiagnosticsManager) Attach(documents *Documents) func() {
+	return documents.Subscribe(func(event DocumentEvent) {
+		switch event.Kind {
+		case DocumentChanged:
+			self.postpone(event.Document.URI)
+		case DocumentClosed:
+			self.Clear(event.Document.URI)
+		}
+	})
+}
+
+// Replaces the diagnostics from the source for the document. The source will be set for
+// diagnostics that do not have one.
+//
+// Version can be nil. Submissions for a version older than the latest submitted version
+// are ignored. A submission for a newer version discards the diagnostics that other sources
+// submitted for older versions, because they are likely no longer correct. (Diagnostics
+// submitted without a version are kept.)
+func (self *DiagnosticsManager) Submit(uri DocumentUri, version *Integer, source string, diagnostics []Diagnostic) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	document, ok := self.documents[uri]
+	if !ok {
+		document = &documentDiagnostics{sources: make(map[string]*sourceDiagnostics)}
+		self.documents[uri] = document
+	}
+
+	var sourceVersion *Integer
+	if version != nil {
+		if document.version != nil {
+			if *version < *document.version {
+				return
+			} else if *version > *document.version {
+				for source_, submitted := range document.sources {
+					if (submitted.version != nil) && (*submitted.version < *version) {
+						delete(document.sources, source_)
+					}
+				}
+			}
+		}
+		version_ := *version
+		document.version = &version_
+		sourceVersion = &version_
+	}
+
+	diagnostics_ := make([]Diagnostic, len(diagnostics))
+	for index, diagnostic := range diagnostics {
+		if diagnostic.Source == nil {
+			source_ := source
+			diagnostic.Source = &source_
+		}
+		diagnostics_[index] = diagnostic
+	}
+	document.sources[source] = &sourceDiagnostics{version: sourceVersion, diagnostics: diagnostics_}
+
+	self.schedule(uri, document)
+}
+
+// Publishes an empty set of diagnostics immediately and forgets the document.
+func (self *DiagnosticsManager) Clear(uri DocumentUri) {
+	self.publishLock.Lock()
+	defer self.publishLock.Unlock()
+
+	self.lock.Lock()
+	if document, ok := self.documents[uri]; ok {
+		if document.timer != nil {
+			document.timer.Stop()
+			document.timer = nil
+		}
+		delete(self.documents, uri)
+	}
+	self.lock.Unlock()
+
+	self.Notify(ServerTextDocumentPublishDiagnostics, &PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+// Publishes pending diagnostics for the document immediately.
+func (self *DiagnosticsManager) Flush(uri DocumentUri) {
+	self.publishLock.Lock()
+	defer self.publishLock.Unlock()
+
+	self.lock.Lock()
+	document, ok := self.documents[uri]
+	if !ok || (document.timer == nil) {
+		self.lock.Unlock()
+		return
+	}
+	document.timer.Stop()
+	document.timer = nil
+	params := self.newParams(uri, document)
+	self.lock.Unlock()
+
+	self.Notify(ServerTextDocumentPublishDiagnostics, params)
+}
+
+// Call while locked
+func (self *DiagnosticsManager) schedule(uri DocumentUri, document *documentDiagnostics) {
+	if document.timer != nil {
+		document.timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(self.Delay, func() {
+		self.publishLock.Lock()
+		defer self.publishLock.Unlock()
+
+		self.lock.Lock()
+		if document.timer != timer {
+			// Superseded (or cleared)
+			self.lock.Unlock()
+			return
+		}
+		document.timer = nil
+		params := self.newParams(uri, document)
+		self.lock.Unlock()
+
+		self.Notify(ServerTextDocumentPublishDiagnostics, params)
+	})
+	document.timer = timer
+}
+
+func (self *DiagnosticsManager) postpone(uri DocumentUri) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if document, ok := self.documents[uri]; ok && (document.timer != nil) {
+		self.schedule(uri, document)
+	}
+}
+
+// Call while locked
+func (self *DiagnosticsManager) newParams(uri DocumentUri, document *documentDiagnostics) *PublishDiagnosticsParams {
+	// Sorted for deterministic results
+	sources := make([]string, 0, len(document.sources))
+	for source := range document.sources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	diagnostics := []Diagnostic{}
+	for _, source := range sources {
+		for _, diagnostic := range document.sources[source].diagnostics {
+			diagnostics = append(diagnostics, self.adapt(diagnostic))
+		}
+	}
+
+	params := PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diagnostics,
+	}
+
+	if (document.version != nil) && ((self.Capabilities == nil) || isTrue(self.Capabilities.VersionSupport)) {
+		version := UInteger(*document.version)
+		params.Version = &version
+	}
+
+	return &params
+}
+
+func (self *DiagnosticsManager) adapt(diagnostic Diagnostic) Diagnostic {
+	if self.Capabilities == nil {
+		return diagnostic
+	}
+
+	if !isTrue(self.Capabilities.RelatedInformation) {
+		diagnostic.RelatedInformation = nil
+	}
+
+	if !isTrue(self.Capabilities.CodeDescriptionSupport) {
+		diagnostic.CodeDescription = nil
+	}
+
+	if !isTrue(self.Capabilities.DataSupport) {
+		diagnostic.Data = nil
+	}
+
+	if self.Capabilities.TagSupport == nil {
+		diagnostic.Tags = nil
+	} else if diagnostic.Tags != nil {
+		var tags []DiagnosticTag
+		for _, tag := range diagnostic.Tags {
+			for _, tag_ := range self.Capabilities.TagSupport.ValueSet {
+				if tag == tag_ {
+					tags = append(tags, tag)
+					break
+				}
+			}
+		}
+		diagnostic.Tags = tags
+	}
+
+	return diagnostic
+}
@@ -0,0 +1,103 @@
+package protocol
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type publications struct {
+	params []*PublishDiagnosticsParams
+	lock   sync.Mutex
+}
+
+func (self *publications) notify(method string, params any) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.params = append(self.params, params.(*PublishDiagnosticsParams))
+}
+
+func (self *publications) last() *PublishDiagnosticsParams {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if len(self.params) == 0 {
+		return nil
+	}
+	return self.params[len(self.params)-1]
+}
+
+func (self *publications) count() int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return len(self.params)
+}
+
+func TestDiagnosticsManagerSources(t *testing.T) {
+	var publications publications
+	manager := NewDiagnosticsManager(publications.notify)
+	manager.Delay = time.Hour
+
+	uri := DocumentUri("file:///test.go")
+	version1, version2 := Integer(1), Integer(2)
+
+	manager.Submit(uri, &version1, "lint", []Diagnostic{{Message: "lint 1"}})
+	manager.Submit(uri, &version1, "vet", []Diagnostic{{Message: "vet 1"}})
+	manager.Submit(uri, nil, "spell", []Diagnostic{{Message: "spell"}})
+	manager.Flush(uri)
+
+	if params := publications.last(); (params == nil) || (len(params.Diagnostics) != 3) || (*params.Version != 1) {
+		t.Fatalf("unexpected publication: %+v", params)
+	}
+
+	// A newer version drops the other sources' older diagnostics, but not unversioned ones
+	manager.Submit(uri, &version2, "lint", []Diagnostic{{Message: "lint 2"}})
+	// An older version is ignored
+	manager.Submit(uri, &version1, "vet", []Diagnostic{{Message: "vet 1 again"}})
+	manager.Flush(uri)
+
+	params := publications.last()
+	var messages []string
+	for _, diagnostic := range params.Diagnostics {
+		messages = append(messages, diagnostic.Message)
+	}
+	if (len(messages) != 2) || (messages[0] != "lint 2") || (messages[1] != "spell") || (*params.Version != 2) {
+		t.Errorf("unexpected publication: %v (version %d)", messages, *params.Version)
+	}
+	if *params.Diagnostics[0].Source != "lint" {
+		t.Errorf("source not set: %v", params.Diagnostics[0].Source)
+	}
+}
+
+func TestDiagnosticsManagerDebounce(t *testing.T) {
+	var publications publications
+	manager := NewDiagnosticsManager(publications.notify)
+	manager.Delay = 20 * time.Millisecond
+
+	uri := DocumentUri("file:///test.go")
+	for count := 0; count < 5; count++ {
+		manager.Submit(uri, nil, "lint", []Diagnostic{{Message: "lint"}})
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if count := publications.count(); count != 1 {
+		t.Errorf("published %d times", count)
+	}
+}
+
+func TestDiagnosticsManagerClear(t *testing.T) {
+	for iteration := 0; iteration < 200; iteration++ {
+		var publications publications
+		manager := NewDiagnosticsManager(publications.notify)
+		manager.Delay = 0
+
+		uri := DocumentUri("file:///test.go")
+		manager.Submit(uri, nil, "lint", []Diagnostic{{Message: "lint"}})
+		manager.Clear(uri)
+
+		// A publication that was in progress must not overtake the clear
+		time.Sleep(time.Millisecond)
+		if params := publications.last(); len(params.Diagnostics) != 0 {
+			t.Fatalf("cleared diagnostics were published after the clear")
+		}
+	}
+}
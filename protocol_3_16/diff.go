@@ -0,0 +1,273 @@
+package protocol
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type DiffGranularity int
+
+const (
+	// Every edit replaces whole lines
+	DiffLines = DiffGranularity(iota)
+
+	// Changed lines are further diffed by character (unless there are too many of them, in
+	// which case they are replaced whole)
+	DiffCharacters
+)
+
+// Maximum total bytes of the old and new lines of a hunk for it to be diffed by character
+const diffMaxCharacterHunk = 8192
+
+// Computes minimal, sorted, non-overlapping edits that would turn the old content into
+// the new content when applied with [ApplyTextEdits]. Positions are in UTF-16 code units.
+//
+// Useful for formatting handlers: sending a minimal diff rather than replacing the whole
+// document lets the client preserve cursors, selections, folds, etc.
+func Diff(oldContent string, newContent string, granularity DiffGranularity) []TextEdit {
+	return DiffWithCodeUnits(oldContent, newContent, granularity, UTF16CodeUnits)
+}
+
+func DiffWithCodeUnits(oldContent string, newContent string, granularity DiffGranularity, codeUnits CodeUnitsFunc) []TextEdit {
+	if oldContent == newContent {
+		return nil
+	}
+
+	oldLines := splitLinesKeepEOL(oldContent)
+	newLines := splitLinesKeepEOL(newContent)
+	oldOffsets := tokenOffsets(oldLines)
+	newOffsets := tokenOffsets(newLines)
+
+	lineIndex := NewLineIndexWithCodeUnits(oldContent, codeUnits)
+	var edits []TextEdit
+
+	for _, hunk := range diffHunks(oldLines, newLines) {
+		// Byte offsets
+		oldStart, oldEnd := oldOffsets[hunk.aStart], oldOffsets[hunk.aEnd]
+		newStart, newEnd := newOffsets[hunk.bStart], newOffsets[hunk.bEnd]
+
+		// Large hunks are not diffed by character, because it would be too slow
+		if (granularity == DiffCharacters) && ((oldEnd-oldStart)+(newEnd-newStart) <= diffMaxCharacterHunk) {
+			oldRunes := []rune(oldContent[oldStart:oldEnd])
+			newRunes := []rune(newContent[newStart:newEnd])
+			oldRuneOffsets := runeOffsets(oldRunes, oldStart)
+
+			for _, hunk_ := range diffHunks(oldRunes, newRunes) {
+				edits = append(edits, TextEdit{
+					Range:   lineIndex.RangeOf(oldRuneOffsets[hunk_.aStart], oldRuneOffsets[hunk_.aEnd]),
+					NewText: string(newRunes[hunk_.bStart:hunk_.bEnd]),
+				})
+			}
+		} else {
+			edits = append(edits, TextEdit{
+				Range:   lineIndex.RangeOf(oldStart, oldEnd),
+				NewText: newContent[newStart:newEnd],
+			})
+		}
+	}
+
+	return edits
+}
+
+// Every line includes its "\n" (except perhaps the last line)
+func splitLinesKeepEOL(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Byte offsets of the tokens, plus the end
+func tokenOffsets(tokens []string) []int {
+	offsets := make([]int, len(tokens)+1)
+	for index, token := range tokens {
+		offsets[index+1] = offsets[index] + len(token)
+	}
+	return offsets
+}
+
+// Byte offsets of the runes, plus the end
+func runeOffsets(runes []rune, start int) []int {
+	offsets := make([]int, len(runes)+1)
+	offsets[0] = start
+	for index, r := range runes {
+		offsets[index+1] = offsets[index] + utf8.RuneLen(r)
+	}
+	return offsets
+}
+
+//
+// diffHunk
+//
+
+// Replaces a[aStart:aEnd] with b[bStart:bEnd]
+type diffHunk struct {
+	aStart int
+	aEnd   int
+	bStart int
+	bEnd   int
+}
+
+func diffHunks[T comparable](a []T, b []T) []diffHunk {
+	// Trimming the common prefix and suffix makes the common case much faster
+	prefix := 0
+	for (prefix < len(a)) && (prefix < len(b)) && (a[prefix] == b[prefix]) {
+		prefix++
+	}
+
+	suffix := 0
+	for (suffix < len(a)-prefix) && (suffix < len(b)-prefix) && (a[len(a)-1-suffix] == b[len(b)-1-suffix]) {
+		suffix++
+	}
+
+	hunks := myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for index := range hunks {
+		hunks[index].aStart += prefix
+		hunks[index].aEnd += prefix
+		hunks[index].bStart += prefix
+		hunks[index].bEnd += prefix
+	}
+
+	return hunks
+}
+
+// See: Eugene W. Myers, "An O(ND) Difference Algorithm and Its Variations" (1986)
+//
+// This is the linear space variant (section 4b): we find the middle snake of an optimal
+// path by searching forward and backward at the same time, and then recurse on both sides
+// of it.
+func myersDiff[T comparable](a []T, b []T) []diffHunk {
+	var hunks []diffHunk
+	myersDivide(a, b, 0, 0, &hunks)
+	return hunks
+}
+
+// The offsets are those of a and b in the original sequences.
+func myersDivide[T comparable](a []T, b []T, aOffset int, bOffset int, hunks *[]diffHunk) {
+	for (len(a) > 0) && (len(b) > 0) && (a[0] == b[0]) {
+		a, b = a[1:], b[1:]
+		aOffset++
+		bOffset++
+	}
+
+	for (len(a) > 0) && (len(b) > 0) && (a[len(a)-1] == b[len(b)-1]) {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	n, m := len(a), len(b)
+	if (n == 0) && (m == 0) {
+		return
+	} else if (n == 0) || (m == 0) {
+		addDiffHunk(hunks, diffHunk{aOffset, aOffset + n, bOffset, bOffset + m})
+		return
+	}
+
+	if x, y, ok := myersMiddle(a, b); ok {
+		myersDivide(a[:x], b[:y], aOffset, bOffset, hunks)
+		myersDivide(a[x:], b[y:], aOffset+x, bOffset+y, hunks)
+	} else {
+		addDiffHunk(hunks, diffHunk{aOffset, aOffset + n, bOffset, bOffset + m})
+	}
+}
+
+// Returns a point on an optimal path at which to split the problem. a and b must not be
+// empty and must not have a common prefix or suffix.
+func myersMiddle[T comparable](a []T, b []T) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+
+	// Furthest x (counted from the start for forward and from the end for backward)
+	// for diagonal k is at [offset+k]
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for index := range forward {
+		forward[index] = -1
+		backward[index] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+
+	delta := n - m
+	// If delta is odd the paths can only meet while going forward, otherwise backward
+	odd := delta%2 != 0
+
+	// Diagonals that went beyond the edges are skipped
+	var forwardStart, forwardEnd, backwardStart, backwardEnd int
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			var x int
+			if (k == -d) || ((k != d) && (forward[offset+k-1] < forward[offset+k+1])) {
+				x = forward[offset+k+1] // down: insertion
+			} else {
+				x = forward[offset+k-1] + 1 // right: deletion
+			}
+
+			y := x - k
+			for (x < n) && (y < m) && (a[x] == b[y]) {
+				x++
+				y++
+			}
+
+			forward[offset+k] = x
+			if x > n {
+				forwardEnd += 2
+			} else if y > m {
+				forwardStart += 2
+			} else if odd {
+				if backwardK := offset + delta - k; (backwardK >= 0) && (backwardK < len(backward)) && (backward[backwardK] != -1) {
+					if x >= n-backward[backwardK] {
+						return x, y, true
+					}
+				}
+			}
+		}
+
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			var x int
+			if (k == -d) || ((k != d) && (backward[offset+k-1] < backward[offset+k+1])) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+
+			y := x - k
+			for (x < n) && (y < m) && (a[n-x-1] == b[m-y-1]) {
+				x++
+				y++
+			}
+
+			backward[offset+k] = x
+			if x > n {
+				backwardEnd += 2
+			} else if y > m {
+				backwardStart += 2
+			} else if !odd {
+				if forwardK := offset + delta - k; (forwardK >= 0) && (forwardK < len(forward)) && (forward[forwardK] != -1) {
+					forwardX := forward[forwardK]
+					if forwardX >= n-x {
+						return forwardX, offset + forwardX - forwardK, true
+					}
+				}
+			}
+		}
+	}
+
+	// No common elements
+	return 0, 0, false
+}
+
+// Merges with the previous hunk if adjacent.
+func addDiffHunk(hunks *[]diffHunk, hunk diffHunk) {
+	if last := len(*hunks) - 1; (last >= 0) && ((*hunks)[last].aEnd == hunk.aStart) && ((*hunks)[last].bEnd == hunk.bStart) {
+		(*hunks)[last].aEnd = hunk.aEnd
+		(*hunks)[last].bEnd = hunk.bEnd
+	} else {
+		*hunks = append(*hunks, hunk)
+	}
+}
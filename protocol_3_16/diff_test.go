@@ -0,0 +1,141 @@
+package protocol
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	var large, largeReformatted strings.Builder
+	for index := 0; index < 2000; index++ {
+		fmt.Fprintf(&large, "func f%d() {return %d}\n", index, index)
+		fmt.Fprintf(&largeReformatted, "func f%d() {\n\treturn %d\n}\n", index, index)
+	}
+
+	tests := []struct {
+		name string
+		old  string
+		new  string
+	}{
+		{"empty", "", ""},
+		{"insert into empty", "", "hello\n"},
+		{"delete all", "hello\nworld\n", ""},
+		{"same", "same\n", "same\n"},
+		{"change line", "one\ntwo\nthree\n", "one\n2\nthree\n"},
+		{"insert lines", "one\nthree\n", "one\ntwo\nthree\nfour\n"},
+		{"no final newline", "one\ntwo", "one\ntwo\n"},
+		{"crlf", "one\r\ntwo\r\nthree\r\n", "one\r\n2\r\nthree\r\nfour\r\n"},
+		{"crlf to lf", "one\r\ntwo\r\n", "one\ntwo\n"},
+		{"non-bmp", "a😀b\n😀😀\nc\n", "a😁b\n😀x😀\nc😀\n"},
+		{"combining", "é\n", "é\n"},
+		{"large reformat", large.String(), largeReformatted.String()},
+		{"large hunk", strings.Repeat("x", 20000) + "\n", strings.Repeat("y", 20000) + "\n"},
+	}
+
+	for _, test := range tests {
+		for _, granularity := range []DiffGranularity{DiffLines, DiffCharacters} {
+			checkDiff(t, fmt.Sprintf("%s (granularity %d)", test.name, granularity), test.old, test.new, granularity)
+		}
+	}
+}
+
+func TestDiffCharacters(t *testing.T) {
+	edits := Diff("hello world\n", "hello there world\n", DiffCharacters)
+	if (len(edits) != 1) || (edits[0].NewText != "there ") || (edits[0].Range != Range{Start: Position{0, 6}, End: Position{0, 6}}) {
+		t.Errorf("unexpected edits: %+v", edits)
+	}
+
+	// UTF-16 positions
+	edits = Diff("😀a\n", "😀b\n", DiffCharacters)
+	if (len(edits) != 1) || (edits[0].Range != Range{Start: Position{0, 2}, End: Position{0, 3}}) {
+		t.Errorf("unexpected edits: %+v", edits)
+	}
+}
+
+func TestDiffRandom(t *testing.T) {
+	lines := []string{"a\n", "b\n", "c\r\n", "😀\n", "ab\n", ""}
+	random := rand.New(rand.NewSource(1))
+	randomContent := func() string {
+		var builder strings.Builder
+		for count := random.Intn(12); count > 0; count-- {
+			builder.WriteString(lines[random.Intn(len(lines))])
+		}
+		return builder.String()
+	}
+
+	for iteration := 0; iteration < 1000; iteration++ {
+		old, new := randomContent(), randomContent()
+		checkDiff(t, fmt.Sprintf("%q -> %q (lines)", old, new), old, new, DiffLines)
+		checkDiff(t, fmt.Sprintf("%q -> %q (characters)", old, new), old, new, DiffCharacters)
+	}
+}
+
+// Hunks must transform a into b with the minimal number of deletions and insertions
+func TestDiffHunksMinimal(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	for iteration := 0; iteration < 5000; iteration++ {
+		alphabet := random.Intn(5) + 1
+		a := make([]int, random.Intn(25))
+		b := make([]int, random.Intn(25))
+		for index := range a {
+			a[index] = random.Intn(alphabet)
+		}
+		for index := range b {
+			b[index] = random.Intn(alphabet)
+		}
+
+		hunks := diffHunks(a, b)
+
+		var result []int
+		position := 0
+		distance := 0
+		for _, hunk := range hunks {
+			if (hunk.aStart < position) || (hunk.aEnd < hunk.aStart) || (hunk.bEnd < hunk.bStart) {
+				t.Fatalf("%v -> %v: invalid hunks %v", a, b, hunks)
+			}
+			result = append(result, a[position:hunk.aStart]...)
+			result = append(result, b[hunk.bStart:hunk.bEnd]...)
+			position = hunk.aEnd
+			distance += (hunk.aEnd - hunk.aStart) + (hunk.bEnd - hunk.bStart)
+		}
+		result = append(result, a[position:]...)
+
+		if fmt.Sprint(result) != fmt.Sprint(b) {
+			t.Fatalf("%v -> %v: hunks %v result in %v", a, b, hunks, result)
+		}
+
+		if expected := len(a) + len(b) - 2*longestCommonSubsequence(a, b); distance != expected {
+			t.Fatalf("%v -> %v: hunks %v are not minimal (%d instead of %d)", a, b, hunks, distance, expected)
+		}
+	}
+}
+
+func checkDiff(t *testing.T, name string, old string, new string, granularity DiffGranularity) {
+	t.Helper()
+
+	edits := Diff(old, new, granularity)
+	if result, err := ApplyTextEdits(old, edits); err != nil {
+		t.Errorf("%s: %s", name, err.Error())
+	} else if result != new {
+		t.Errorf("%s: got %q", name, result)
+	}
+}
+
+func longestCommonSubsequence(a []int, b []int) int {
+	table := make([][]int, len(a)+1)
+	for index := range table {
+		table[index] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				table[i][j] = table[i-1][j-1] + 1
+			} else {
+				table[i][j] = max(table[i-1][j], table[i][j-1])
+			}
+		}
+	}
+	return table[len(a)][len(b)]
+}
@@ -0,0 +1,310 @@
+package protocol
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/tliron/glsp"
+)
+
+//
+// Document
+//
+
+// An immutable snapshot of an open text document
+type Document struct {
+	URI        DocumentUri
+	LanguageID string
+	Version    Integer
+	Content    string
+
+	lineIndex *LineIndex
+}
+
+// The index is built once per version.
+func (self *Document) LineIndex() *LineIndex {
+	if self.lineIndex == nil {
+		// Documents not created by the store
+		self.lineIndex = NewLineIndex(self.Content)
+	}
+	return self.lineIndex
+}
+
+//
+// DocumentEvent
+//
+
+type DocumentEventKind int
+
+const (
+	DocumentOpened = DocumentEventKind(iota)
+	DocumentChanged
+	DocumentClosed
+)
+
+type DocumentEvent struct {
+	Kind     DocumentEventKind
+	Document Document // for DocumentClosed this is the last known snapshot
+}
+
+type DocumentListener func(event DocumentEvent)
+
+//
+// Documents
+//
+
+// A concurrency-safe store of open text documents that is kept up to date by
+// "textDocument/didOpen", "textDocument/didChange", and "textDocument/didClose".
+//
+// Use [Documents.Attach] to plug it into a [Handler].
+type Documents struct {
+	// Used for all positions; defaults to UTF-16
+	CodeUnits CodeUnitsFunc
+
+	documents map[DocumentUri]Document
+	listeners map[uint64]DocumentListener
+	nextID    uint64
+	lock      sync.RWMutex
+}
+
+func NewDocuments() *Documents {
+	return &Documents{
+		documents: make(map[DocumentUri]Document),
+		listeners: make(map[uint64]DocumentListener),
+	}
+}
+
+// Sets the handler's TextDocumentDidOpen, TextDocumentDidChange, and TextDocumentDidClose
+// to update the store. Previously set functions will be called after the store is updated.
+func (self *Documents) Attach(handler *Handler) {
+	didOpen := handler.TextDocumentDidOpen
+	handler.TextDocumentDidOpen = func(context *glsp.Context, params *DidOpenTextDocumentParams) error {
+		self.DidOpen(params)
+		if didOpen != nil {
+			return didOpen(context, params)
+		}
+		return nil
+	}
+
+	didChange := handler.TextDocumentDidChange
+	handler.TextDocumentDidChange = func(context *glsp.Context, params *DidChangeTextDocumentParams) error {
+		if err := self.DidChange(params); err != nil {
+			return err
+		}
+		if didChange != nil {
+			return didChange(context, params)
+		}
+		return nil
+	}
+
+	didClose := handler.TextDocumentDidClose
+	handler.TextDocumentDidClose = func(context *glsp.Context, params *DidCloseTextDocumentParams) error {
+		self.DidClose(params)
+		if didClose != nil {
+			return didClose(context, params)
+		}
+		return nil
+	}
+}
+
+func (self *Documents) Get(uri DocumentUri) (Document, bool) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	document, ok := self.documents[uri]
+	return document, ok
+}
+
+func (self *Documents) List() []Document {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	documents := make([]Document, 0, len(self.documents))
+	for _, document := range self.documents {
+		documents = append(documents, document)
+	}
+	return documents
+}
+
+// The listener will be called synchronously after every change to the store.
+// Call the returned function to unsubscribe.
+func (self *Documents) Subscribe(listener DocumentListener) func() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	id := self.nextID
+	self.nextID++
+	self.listeners[id] = listener
+	return func() {
+		self.lock.Lock()
+		defer self.lock.Unlock()
+		delete(self.listeners, id)
+	}
+}
+
+func (self *Documents) DidOpen(params *DidOpenTextDocumentParams) {
+	document := Document{
+		URI:        params.TextDocument.URI,
+		LanguageID: params.TextDocument.LanguageID,
+		Version:    params.TextDocument.Version,
+		Content:    params.TextDocument.Text,
+	}
+	document.lineIndex = self.newLineIndex(document.Content)
+
+	self.lock.Lock()
+	self.documents[document.URI] = document
+	listeners := self.getListeners()
+	self.lock.Unlock()
+
+	notifyDocumentListeners(listeners, DocumentEvent{DocumentOpened, document})
+}
+
+// Will return an error if the document is not open, if the version is not newer than
+// the current version, or if a change cannot be applied. In such cases the store is
+// not modified.
+func (self *Documents) DidChange(params *DidChangeTextDocumentParams) error {
+	self.lock.Lock()
+
+	document, ok := self.documents[params.TextDocument.URI]
+	if !ok {
+		self.lock.Unlock()
+		return fmt.Errorf("document not open: %s", params.TextDocument.URI)
+	}
+
+	if params.TextDocument.Version <= document.Version {
+		self.lock.Unlock()
+		return fmt.Errorf("out-of-order version for %s: %d after %d", document.URI, params.TextDocument.Version, document.Version)
+	}
+
+	lineIndex := document.lineIndex
+	if lineIndex == nil {
+		lineIndex = self.newLineIndex(document.Content)
+	}
+
+	content, lineIndex, err := applyContentChanges(document.Content, lineIndex, params.ContentChanges, self.getCodeUnits())
+	if err != nil {
+		self.lock.Unlock()
+		return fmt.Errorf("could not change %s: %w", document.URI, err)
+	}
+
+	if lineIndex == nil {
+		lineIndex = self.newLineIndex(content)
+	}
+
+	document.Version = params.TextDocument.Version
+	document.Content = content
+	document.lineIndex = lineIndex
+	self.documents[document.URI] = document
+	listeners := self.getListeners()
+	self.lock.Unlock()
+
+	notifyDocumentListeners(listeners, DocumentEvent{DocumentChanged, document})
+	return nil
+}
+
+func (self *Documents) DidClose(params *DidCloseTextDocumentParams) {
+	self.lock.Lock()
+	document, ok := self.documents[params.TextDocument.URI]
+	if !ok {
+		self.lock.Unlock()
+		return
+	}
+	delete(self.documents, document.URI)
+	listeners := self.getListeners()
+	self.lock.Unlock()
+
+	notifyDocumentListeners(listeners, DocumentEvent{DocumentClosed, document})
+}
+
+func (self *Documents) getCodeUnits() CodeUnitsFunc {
+	if self.CodeUnits != nil {
+		return self.CodeUnits
+	} else {
+		return UTF16CodeUnits
+	}
+}
+
+func (self *Documents) newLineIndex(content string) *LineIndex {
+	return NewLineIndexWithCodeUnits(content, self.getCodeUnits())
+}
+
+// Call while locked
+func (self *Documents) getListeners() []DocumentListener {
+	listeners := make([]DocumentListener, 0, len(self.listeners))
+	for _, listener := range self.listeners {
+		listeners = append(listeners, listener)
+	}
+	return listeners
+}
+
+func notifyDocumentListeners(listeners []DocumentListener, event DocumentEvent) {
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// Applies [TextDocumentContentChangeEvent] and [TextDocumentContentChangeEventWhole] in order.
+// Positions are in UTF-16 code units.
+func ApplyContentChanges(content string, changes []any) (string, error) {
+	return ApplyContentChangesWithCodeUnits(content, changes, UTF16CodeUnits)
+}
+
+func ApplyContentChangesWithCodeUnits(content string, changes []any, codeUnits CodeUnitsFunc) (string, error) {
+	content, _, err := applyContentChanges(content, nil, changes, codeUnits)
+	return content, err
+}
+
+// The line index, if not nil, must be that of the content. It is not modified: a copy of it
+// is updated incrementally with every change rather than rebuilt. The returned line index is
+// that of the returned content, or nil if it has not been built (when there are no ranged
+// changes after the last whole change).
+func applyContentChanges(content string, lineIndex *LineIndex, changes []any, codeUnits CodeUnitsFunc) (string, *LineIndex, error) {
+	if lineIndex != nil {
+		lineIndex = lineIndex.clone()
+	}
+
+	for _, change := range changes {
+		switch change_ := change.(type) {
+		case TextDocumentContentChangeEvent:
+			var err error
+			if content, lineIndex, err = applyContentChange(content, lineIndex, &change_, codeUnits); err != nil {
+				return "", nil, err
+			}
+
+		case *TextDocumentContentChangeEvent:
+			var err error
+			if content, lineIndex, err = applyContentChange(content, lineIndex, change_, codeUnits); err != nil {
+				return "", nil, err
+			}
+
+		case TextDocumentContentChangeEventWhole:
+			content = change_.Text
+			lineIndex = nil
+
+		case *TextDocumentContentChangeEventWhole:
+			content = change_.Text
+			lineIndex = nil
+
+		default:
+			return "", nil, fmt.Errorf("unsupported content change: %T", change)
+		}
+	}
+
+	return content, lineIndex, nil
+}
+
+// The line index, if not nil, is modified.
+func applyContentChange(content string, lineIndex *LineIndex, change *TextDocumentContentChangeEvent, codeUnits CodeUnitsFunc) (string, *LineIndex, error) {
+	if change.Range == nil {
+		return change.Text, nil, nil
+	}
+
+	if lineIndex == nil {
+		lineIndex = NewLineIndexWithCodeUnits(content, codeUnits)
+	}
+
+	start, end := lineIndex.IndexesOf(*change.Range)
+	if start > end {
+		return "", nil, fmt.Errorf("invalid range: %s", rangeString(*change.Range))
+	}
+
+	lineIndex.replace(start, end, change.Text)
+	return lineIndex.content, lineIndex, nil
+}
@@ -0,0 +1,204 @@
+package protocol
+
+import (
+	"encoding/json"
+)
+
+// Converts the result of a request into a form that the client supports, according to its
+// capabilities, so that handlers can always return the richest form. This is called
+// automatically by [Handler].
+//
+// Supported conversions:
+//   - []LocationLink to []Location for declaration, definition, type definition, and
+//     implementation
+//   - []DocumentSymbol to a flat []SymbolInformation for document symbols
+//   - []CodeAction to []Command for code actions (code actions without a command are
+//     dropped)
+//   - markdown MarkupContent to plaintext for hover, completion, and signature help
+//
+// Results are never modified in place; converted results are copies. If capabilities is nil
+// the result is returned as is.
+func DowngradeResult(method Method, params json.RawMessage, result any, capabilities *ClientCapabilities) any {
+	if (capabilities == nil) || (result == nil) {
+		return result
+	}
+
+	switch method {
+	case MethodTextDocumentDeclaration, MethodTextDocumentDefinition, MethodTextDocumentTypeDefinition, MethodTextDocumentImplementation:
+		if links, ok := result.([]LocationLink); ok && !capabilities.SupportsLocationLinks(method) {
+			return toLocations(links)
+		}
+
+	case MethodTextDocumentDocumentSymbol:
+		if symbols, ok := result.([]DocumentSymbol); ok && !capabilities.SupportsHierarchicalDocumentSymbols() {
+			var params_ DocumentSymbolParams
+			if err := json.Unmarshal(params, &params_); err == nil {
+				return toSymbolInformation(params_.TextDocument.URI, symbols)
+			}
+		}
+
+	case MethodTextDocumentCodeAction:
+		if !capabilities.SupportsCodeActionLiterals() {
+			switch result_ := result.(type) {
+			case []CodeAction:
+				commands := make([]Command, 0, len(result_))
+				for _, codeAction := range result_ {
+					if codeAction.Command != nil {
+						commands = append(commands, *codeAction.Command)
+					}
+				}
+				return commands
+
+			case []any:
+				commands := make([]Command, 0, len(result_))
+				for _, element := range result_ {
+					switch element_ := element.(type) {
+					case Command:
+						commands = append(commands, element_)
+					case *Command:
+						commands = append(commands, *element_)
+					case CodeAction:
+						if element_.Command != nil {
+							commands = append(commands, *element_.Command)
+						}
+					case *CodeAction:
+						if element_.Command != nil {
+							commands = append(commands, *element_.Command)
+						}
+					}
+				}
+				return commands
+			}
+		}
+
+	case MethodTextDocumentHover:
+		if !capabilities.SupportsHoverMarkdown() {
+			if hover, ok := result.(*Hover); ok && (hover != nil) {
+				hover_ := *hover
+				hover_.Contents = toPlainText(hover.Contents)
+				return &hover_
+			}
+		}
+
+	case MethodTextDocumentCompletion:
+		if !capabilities.SupportsCompletionDocumentationMarkdown() {
+			switch result_ := result.(type) {
+			case []CompletionItem:
+				return toPlainTextCompletionItems(result_)
+
+			case CompletionList:
+				result_.Items = toPlainTextCompletionItems(result_.Items)
+				return result_
+
+			case *CompletionList:
+				if result_ != nil {
+					list := *result_
+					list.Items = toPlainTextCompletionItems(list.Items)
+					return &list
+				}
+			}
+		}
+
+	case MethodCompletionItemResolve:
+		if !capabilities.SupportsCompletionDocumentationMarkdown() {
+			if item, ok := result.(*CompletionItem); ok && (item != nil) {
+				item_ := *item
+				item_.Documentation = toPlainText(item.Documentation)
+				return &item_
+			}
+		}
+
+	case MethodTextDocumentSignatureHelp:
+		if !capabilities.SupportsSignatureHelpDocumentationMarkdown() {
+			if signatureHelp, ok := result.(*SignatureHelp); ok && (signatureHelp != nil) {
+				signatureHelp_ := *signatureHelp
+				signatureHelp_.Signatures = make([]SignatureInformation, len(signatureHelp.Signatures))
+				for index, signature := range signatureHelp.Signatures {
+					signature.Documentation = toPlainText(signature.Documentation)
+					if signature.Parameters != nil {
+						parameters := make([]ParameterInformation, len(signature.Parameters))
+						for index_, parameter := range signature.Parameters {
+							parameter.Documentation = toPlainText(parameter.Documentation)
+							parameters[index_] = parameter
+						}
+						signature.Parameters = parameters
+					}
+					signatureHelp_.Signatures[index] = signature
+				}
+				return &signatureHelp_
+			}
+		}
+	}
+
+	return result
+}
+
+func toLocations(links []LocationLink) []Location {
+	locations := make([]Location, len(links))
+	for index, link := range links {
+		locations[index] = Location{
+			URI:   link.TargetURI,
+			Range: link.TargetSelectionRange,
+		}
+	}
+	return locations
+}
+
+func toSymbolInformation(uri DocumentUri, symbols []DocumentSymbol) []SymbolInformation {
+	var symbolInformation []SymbolInformation
+
+	var add func(symbols []DocumentSymbol, containerName *string)
+	add = func(symbols []DocumentSymbol, containerName *string) {
+		for _, symbol := range symbols {
+			symbolInformation = append(symbolInformation, SymbolInformation{
+				Name:       symbol.Name,
+				Kind:       symbol.Kind,
+				Tags:       symbol.Tags,
+				Deprecated: symbol.Deprecated,
+				Location: Location{
+					URI:   uri,
+					Range: symbol.Range,
+				},
+				ContainerName: containerName,
+			})
+
+			if len(symbol.Children) > 0 {
+				name := symbol.Name
+				add(symbol.Children, &name)
+			}
+		}
+	}
+
+	add(symbols, nil)
+
+	if symbolInformation == nil {
+		return []SymbolInformation{}
+	}
+	return symbolInformation
+}
+
+func toPlainTextCompletionItems(items []CompletionItem) []CompletionItem {
+	items_ := make([]CompletionItem, len(items))
+	for index, item := range items {
+		item.Documentation = toPlainText(item.Documentation)
+		items_[index] = item
+	}
+	return items_
+}
+
+// Only markdown MarkupContent is converted. The markdown text is kept as is.
+func toPlainText(content any) any {
+	switch content_ := content.(type) {
+	case MarkupContent:
+		if content_.Kind == MarkupKindMarkdown {
+			return MarkupContent{Kind: MarkupKindPlainText, Value: content_.Value}
+		}
+
+	case *MarkupContent:
+		if (content_ != nil) && (content_.Kind == MarkupKindMarkdown) {
+			return MarkupContent{Kind: MarkupKindPlainText, Value: content_.Value}
+		}
+	}
+
+	return content
+}
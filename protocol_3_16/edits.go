@@ -0,0 +1,81 @@
+package protocol
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Applies the edits to the content. Positions are in UTF-16 code units.
+//
+// As per the spec, all ranges refer to the original content. Edits may not overlap, but
+// several inserts at the same position are applied in the order they were given.
+func ApplyTextEdits(content string, edits []TextEdit) (string, error) {
+	return ApplyTextEditsWithCodeUnits(content, edits, UTF16CodeUnits)
+}
+
+func ApplyTextEditsWithCodeUnits(content string, edits []TextEdit, codeUnits CodeUnitsFunc) (string, error) {
+	if len(edits) == 0 {
+		return content, nil
+	}
+
+	lineIndex := NewLineIndexWithCodeUnits(content, codeUnits)
+
+	type indexedEdit struct {
+		range_  Range
+		start   int
+		end     int
+		newText string
+	}
+
+	edits_ := make([]indexedEdit, len(edits))
+	for index, edit := range edits {
+		start, end := lineIndex.IndexesOf(edit.Range)
+		if start > end {
+			return "", fmt.Errorf("invalid range: %s", rangeString(edit.Range))
+		}
+		edits_[index] = indexedEdit{edit.Range, start, end, edit.NewText}
+	}
+
+	// Stable so that inserts at the same position keep their order
+	sort.SliceStable(edits_, func(i int, j int) bool {
+		return edits_[i].start < edits_[j].start
+	})
+
+	var builder []byte
+	previousEnd := 0
+	for index, edit := range edits_ {
+		if edit.start < previousEnd {
+			return "", fmt.Errorf("overlapping edits: %s and %s", rangeString(edits_[index-1].range_), rangeString(edit.range_))
+		}
+		builder = append(builder, content[previousEnd:edit.start]...)
+		builder = append(builder, edit.newText...)
+		previousEnd = edit.end
+	}
+	builder = append(builder, content[previousEnd:]...)
+
+	return string(builder), nil
+}
+
+// Accepts [TextEdit] and [AnnotatedTextEdit] (as used in [TextDocumentEdit]).
+func ToTextEdits(edits []any) ([]TextEdit, error) {
+	edits_ := make([]TextEdit, len(edits))
+	for index, edit := range edits {
+		switch edit_ := edit.(type) {
+		case TextEdit:
+			edits_[index] = edit_
+		case *TextEdit:
+			edits_[index] = *edit_
+		case AnnotatedTextEdit:
+			edits_[index] = edit_.TextEdit
+		case *AnnotatedTextEdit:
+			edits_[index] = edit_.TextEdit
+		default:
+			return nil, fmt.Errorf("unsupported text edit: %T", edit)
+		}
+	}
+	return edits_, nil
+}
+
+func rangeString(range_ Range) string {
+	return fmt.Sprintf("%d:%d-%d:%d", range_.Start.Line, range_.Start.Character, range_.End.Line, range_.End.Character)
+}
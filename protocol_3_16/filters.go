@@ -0,0 +1,105 @@
+package protocol
+
+import (
+	"github.com/tliron/glsp/glob"
+	"github.com/tliron/glsp/uri"
+)
+
+// Glob patterns are matched against the decoded path of the URI, e.g. "/home/me/file.go".
+
+// Matches if any of the filters match.
+func (self DocumentSelector) Matches(uri DocumentUri, languageID string) bool {
+	for _, filter := range self {
+		if filter.Matches(uri, languageID) {
+			return true
+		}
+	}
+	return false
+}
+
+// All the filter's properties that are set must match. As in VS Code, a filter with no
+// properties set matches nothing.
+func (self *DocumentFilter) Matches(uri_ DocumentUri, languageID string) bool {
+	if (self.Language == nil) && (self.Scheme == nil) && (self.Pattern == nil) {
+		return false
+	}
+
+	if (self.Language != nil) && (*self.Language != languageID) {
+		return false
+	}
+
+	if (self.Scheme != nil) || (self.Pattern != nil) {
+		parsed, err := uri.Parse(uri_)
+		if err != nil {
+			return false
+		}
+
+		if (self.Scheme != nil) && (*self.Scheme != parsed.Scheme) {
+			return false
+		}
+
+		if (self.Pattern != nil) && !glob.Match(*self.Pattern, parsed.Path, false) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (self *FileSystemWatcher) Matches(uri_ DocumentUri) bool {
+	if parsed, err := uri.Parse(uri_); err == nil {
+		return glob.Match(self.GlobPattern, parsed.Path, false)
+	} else {
+		return false
+	}
+}
+
+// Matches if any of the filters match.
+func (self *FileOperationRegistrationOptions) Matches(uri DocumentUri, isFolder bool) bool {
+	for _, filter := range self.Filters {
+		if filter.Matches(uri, isFolder) {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *FileOperationFilter) Matches(uri_ DocumentUri, isFolder bool) bool {
+	parsed, err := uri.Parse(uri_)
+	if err != nil {
+		return false
+	}
+
+	if (self.Scheme != nil) && (*self.Scheme != parsed.Scheme) {
+		return false
+	}
+
+	return self.Pattern.matches(parsed.Path, isFolder)
+}
+
+// (Named so because Matches is a field)
+func (self *FileOperationPattern) MatchesURI(uri_ DocumentUri, isFolder bool) bool {
+	if parsed, err := uri.Parse(uri_); err == nil {
+		return self.matches(parsed.Path, isFolder)
+	} else {
+		return false
+	}
+}
+
+func (self *FileOperationPattern) matches(path string, isFolder bool) bool {
+	if self.Matches != nil {
+		switch *self.Matches {
+		case FileOperationPatternKindFile:
+			if isFolder {
+				return false
+			}
+		case FileOperationPatternKindFolder:
+			if !isFolder {
+				return false
+			}
+		}
+	}
+
+	ignoreCase := (self.Options != nil) && isTrue(self.Options.IgnoreCase)
+	return glob.Match(self.Glob, path, ignoreCase)
+}
@@ -0,0 +1,293 @@
+package protocol
+
+import (
+	"math"
+	"unicode"
+)
+
+// Only this many runes of the pattern and word are considered (like VS Code)
+const fuzzyMaxLength = 128
+
+const fuzzyNoScore = math.MinInt32
+
+type fuzzyArrow int
+
+const (
+	fuzzyArrowDiag fuzzyArrow = iota
+	fuzzyArrowLeft
+	fuzzyArrowLeftLeft
+)
+
+// Scores how well the pattern fuzzily matches the word. Returns false if it doesn't match
+// at all. Higher scores are better.
+//
+// This is a port of VS Code's fuzzyScore (as used for filtering completion items), so that
+// servers that filter items themselves rank them like the client would. In particular, all
+// pattern characters must appear in the word in order (case-insensitively), and the first
+// pattern character must match at the start of the word or at a word boundary (a separator
+// or a camel-case hump). Matching the whole word is boosted and every skipped rune in the
+// word is penalized.
+//
+// An empty pattern matches every word with a score of 0.
+func FuzzyScore(pattern string, word string) (int, bool) {
+	patternRunes := []rune(pattern)
+	wordRunes := []rune(word)
+	if len(patternRunes) > fuzzyMaxLength {
+		patternRunes = patternRunes[:fuzzyMaxLength]
+	}
+	if len(wordRunes) > fuzzyMaxLength {
+		wordRunes = wordRunes[:fuzzyMaxLength]
+	}
+
+	patternLength := len(patternRunes)
+	wordLength := len(wordRunes)
+
+	if patternLength == 0 {
+		return 0, true
+	} else if patternLength > wordLength {
+		return 0, false
+	}
+
+	patternLow := toLowerRunes(patternRunes)
+	wordLow := toLowerRunes(wordRunes)
+
+	// The earliest and latest word positions at which each pattern rune can match
+	minWordPos := make([]int, patternLength)
+	maxWordPos := make([]int, patternLength)
+
+	patternPos := 0
+	for wordPos := 0; (patternPos < patternLength) && (wordPos < wordLength); wordPos++ {
+		if patternLow[patternPos] == wordLow[wordPos] {
+			minWordPos[patternPos] = wordPos
+			patternPos++
+		}
+	}
+	if patternPos < patternLength {
+		// The pattern is not in the word
+		return 0, false
+	}
+
+	patternPos = patternLength - 1
+	for wordPos := wordLength - 1; (patternPos >= 0) && (wordPos >= 0); wordPos-- {
+		if patternLow[patternPos] == wordLow[wordPos] {
+			maxWordPos[patternPos] = wordPos
+			patternPos--
+		}
+	}
+
+	// table[row][column] is the best score for matching pattern[:row] within word[:column];
+	// diag[row][column] is the length of the contiguous match ending there; arrows are for
+	// backtracking
+	table := make([][]int, patternLength+1)
+	diag := make([][]int, patternLength+1)
+	arrows := make([][]fuzzyArrow, patternLength+1)
+	for row := range table {
+		table[row] = make([]int, wordLength+1)
+		diag[row] = make([]int, wordLength+1)
+		arrows[row] = make([]fuzzyArrow, wordLength+1)
+	}
+
+	strongFirstMatch := false
+
+	for row := 1; row <= patternLength; row++ {
+		patternPos := row - 1
+
+		// Only the word positions from which the pattern can still be matched
+		nextMaxWordPos := wordLength
+		if patternPos+1 < patternLength {
+			nextMaxWordPos = maxWordPos[patternPos+1]
+		}
+
+		for wordPos := minWordPos[patternPos]; wordPos < nextMaxWordPos; wordPos++ {
+			column := wordPos + 1
+
+			diagScore := fuzzyNoScore
+			if wordPos <= maxWordPos[patternPos] {
+				if score, strong := fuzzyScoreAt(patternRunes, patternLow, patternPos, wordRunes, wordLow, wordPos, diag[row-1][column-1] == 0); score != fuzzyNoScore {
+					diagScore = table[row-1][column-1] + score
+					if strong && (patternPos == 0) {
+						strongFirstMatch = true
+					}
+				}
+			}
+
+			canComeLeft := wordPos > minWordPos[patternPos]
+			var leftScore int
+			if canComeLeft {
+				leftScore = table[row][column-1]
+				if diag[row][column-1] > 0 {
+					// Penalty for starting a gap
+					leftScore -= 5
+				}
+			}
+
+			canComeLeftLeft := (wordPos > minWordPos[patternPos]+1) && (diag[row][column-1] > 0)
+			var leftLeftScore int
+			if canComeLeftLeft {
+				leftLeftScore = table[row][column-2]
+				if diag[row][column-2] > 0 {
+					// Penalty for starting a gap
+					leftLeftScore -= 5
+				}
+			}
+
+			// We prefer left (and left-left), because it means that the match is earlier in
+			// the word
+			if canComeLeftLeft && (!canComeLeft || (leftLeftScore >= leftScore)) && ((diagScore == fuzzyNoScore) || (leftLeftScore >= diagScore)) {
+				table[row][column] = leftLeftScore
+				arrows[row][column] = fuzzyArrowLeftLeft
+				diag[row][column] = 0
+			} else if canComeLeft && ((diagScore == fuzzyNoScore) || (leftScore >= diagScore)) {
+				table[row][column] = leftScore
+				arrows[row][column] = fuzzyArrowLeft
+				diag[row][column] = 0
+			} else {
+				// The rune always matches at the earliest word position, so we can't get here
+				// without a diagonal score
+				table[row][column] = diagScore
+				arrows[row][column] = fuzzyArrowDiag
+				diag[row][column] = diag[row-1][column-1] + 1
+			}
+		}
+	}
+
+	if !strongFirstMatch {
+		return 0, false
+	}
+
+	score := table[patternLength][wordLength]
+
+	// Find the column of the last matched rune
+	lastMatchColumn := wordLength
+	for lastMatchColumn >= 1 {
+		if arrow := arrows[patternLength][lastMatchColumn]; arrow == fuzzyArrowLeftLeft {
+			lastMatchColumn -= 2
+		} else if arrow == fuzzyArrowLeft {
+			lastMatchColumn--
+		} else {
+			break
+		}
+	}
+
+	if wordLength == patternLength {
+		// Boost for matching all the runes of the word
+		score += 2
+	}
+
+	// Penalty for each skipped rune in the word
+	score -= lastMatchColumn - patternLength
+
+	return score, true
+}
+
+// Returns the score for matching a single pattern rune with a single word rune, and whether
+// it was a strong match (at a word boundary).
+func fuzzyScoreAt(pattern []rune, patternLow []rune, patternPos int, word []rune, wordLow []rune, wordPos int, newMatchStart bool) (int, bool) {
+	if patternLow[patternPos] != wordLow[wordPos] {
+		return fuzzyNoScore, false
+	}
+
+	score := 1
+	isGapLocation := false
+
+	if wordPos == patternPos {
+		// Common prefix: "foobar" <-> "foobaz"
+		if pattern[patternPos] == word[wordPos] {
+			score = 7
+		} else {
+			score = 5
+		}
+	} else if isUpperCaseAt(word, wordLow, wordPos) && ((wordPos == 0) || !isUpperCaseAt(word, wordLow, wordPos-1)) {
+		// Hitting upper-case: "foo" <-> "forOthers"
+		if pattern[patternPos] == word[wordPos] {
+			score = 7
+		} else {
+			score = 5
+		}
+		isGapLocation = true
+	} else if isSeparatorAt(wordLow, wordPos) && ((wordPos == 0) || !isSeparatorAt(wordLow, wordPos-1)) {
+		// Hitting a separator: "." <-> "foo.bar"
+		score = 5
+	} else if isSeparatorAt(wordLow, wordPos-1) || isWhitespaceAt(wordLow, wordPos-1) {
+		// Post separator: "foo" <-> "bar_foo"
+		score = 5
+		isGapLocation = true
+	}
+
+	strong := score > 1
+
+	if !isGapLocation {
+		isGapLocation = isUpperCaseAt(word, wordLow, wordPos) || isSeparatorAt(wordLow, wordPos-1) || isWhitespaceAt(wordLow, wordPos-1)
+	}
+
+	if patternPos == 0 {
+		if wordPos > 0 {
+			// Penalty for the gap preceding the first match
+			if isGapLocation {
+				score -= 3
+			} else {
+				score -= 5
+			}
+		}
+	} else if newMatchStart {
+		// Beginning of a new match after a gap
+		if isGapLocation {
+			score += 2
+		}
+	} else if !isGapLocation {
+		// Bonus for a contiguous match
+		score++
+	}
+
+	if wordPos+1 == len(word) {
+		// Pretend that there is a gap after the last rune in the word, otherwise matching
+		// it would be an unfair advantage
+		if isGapLocation {
+			score -= 3
+		} else {
+			score -= 5
+		}
+	}
+
+	return score, strong
+}
+
+// Utils
+
+func toLowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for index, r := range runes {
+		lower[index] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+func isUpperCaseAt(word []rune, wordLow []rune, pos int) bool {
+	return word[pos] != wordLow[pos]
+}
+
+func isSeparatorAt(wordLow []rune, pos int) bool {
+	if (pos < 0) || (pos >= len(wordLow)) {
+		return false
+	}
+
+	switch wordLow[pos] {
+	case '_', '-', '.', ' ', '/', '\\', '\'', '"', ':', '$', '<', '>', '(', ')', '[', ']', '{', '}':
+		return true
+	default:
+		return false
+	}
+}
+
+func isWhitespaceAt(wordLow []rune, pos int) bool {
+	if (pos < 0) || (pos >= len(wordLow)) {
+		return false
+	}
+
+	switch wordLow[pos] {
+	case ' ', '\t':
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,80 @@
+package protocol
+
+import (
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		pattern string
+		word    string
+		score   int
+		ok      bool
+	}{
+		{"", "anything", 0, true},
+		// "c" 7, "o" 7+1, "n" 7+1, then a gap penalty of 5
+		{"con", "console", 18, true},
+		{"con", "const", 18, true},
+		// As above, but "c" matches "C" with a different case (5)
+		{"con", "Container", 16, true},
+		// Full match boost of 2, but no gap penalty for the last rune
+		{"const", "const", 36, true},
+		{"p", "p", 4, true},
+		// The first rune must match at a word boundary
+		{"con", "iconv", 0, false},
+		{"ob", "foobar", 0, false},
+		{"sl", "SVisualLoggerLogsList", 0, true},
+		{"Three", "HTMLHRElement", 0, false},
+		// Not all runes are in the word
+		{"cox", "console", 0, false},
+		{"consoles", "console", 0, false},
+	}
+
+	for _, test := range tests {
+		score, ok := FuzzyScore(test.pattern, test.word)
+		if ok != test.ok {
+			t.Errorf("%q %q: got match %t, expected %t", test.pattern, test.word, ok, test.ok)
+		} else if ok && (test.score != 0) && (score != test.score) {
+			t.Errorf("%q %q: got score %d, expected %d", test.pattern, test.word, score, test.score)
+		}
+	}
+}
+
+// These are VS Code's own "topScore" tests for fuzzyScore
+func TestFuzzyScoreTop(t *testing.T) {
+	tests := []struct {
+		pattern string
+		top     int
+		words   []string
+	}{
+		{"cons", 2, []string{"ArrayBufferConstructor", "Console", "console"}},
+		{"Foo", 1, []string{"foo", "Foo", "foo"}},
+		{"onMess", 1, []string{"onmessage", "onMessage", "onThisMegaEscape"}},
+		{"CC", 1, []string{"camelCase", "CamelCase"}},
+		{"cC", 0, []string{"camelCase", "CamelCase"}},
+		{"p", 4, []string{"parse", "posix", "pafdsa", "path", "p"}},
+		{"pa", 0, []string{"parse", "pafdsa", "path"}},
+		{"log", 3, []string{"HTMLOptGroupElement", "ScrollLogicalPosition", "SVGFEMorphologyElement", "log", "logger"}},
+		{"e", 2, []string{"AbstractWorker", "ActiveXObject", "else"}},
+		{"workbench.sideb", 1, []string{"workbench.editor.defaultSideBySideLayout", "workbench.sideBar.location"}},
+		{"editor.r", 2, []string{"diffEditor.renderSideBySide", "editor.overviewRulerlanes", "editor.renderControlCharacter", "editor.renderWhitespace"}},
+		{"-mo", 1, []string{"-ms-ime-mode", "-moz-columns"}},
+		{"convertModelPosition", 0, []string{"convertModelPositionToViewPosition", "convertViewToModelPosition"}},
+		{"is", 0, []string{"isValidViewletId", "import statement"}},
+		{"title", 1, []string{"files.trimTrailingWhitespace", "window.title"}},
+		{"const", 1, []string{"constructor", "const", "cuOnstrul"}},
+	}
+
+	for _, test := range tests {
+		// Ties go to the first word
+		top, topScore := -1, 0
+		for index, word := range test.words {
+			if score, ok := FuzzyScore(test.pattern, word); ok && ((top == -1) || (score > topScore)) {
+				top, topScore = index, score
+			}
+		}
+		if top != test.top {
+			t.Errorf("%q: got %d, expected %d (%q)", test.pattern, top, test.top, test.words[test.top])
+		}
+	}
+}
@@ -2,7 +2,6 @@ package protocol
 
 import (
 	"encoding/json"
-	"errors"
 	"sync"
 
 	"github.com/tliron/glsp"
@@ -85,25 +84,27 @@ type Handler struct {
 	// Custom Request/Notification
 	CustomRequest map[string]CustomRequestHandler
 
-	initialized bool
-	lock        sync.Mutex
+	initialized    bool
+	semanticTokens *SemanticTokensCache
+	lock           sync.Mutex
 }
 
 // ([glsp.Handler] interface)
 func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, validParams bool, err error) {
 	if !self.IsInitialized() && (context.Method != MethodInitialize) {
-		return nil, true, true, errors.New("server not initialized")
+		return nil, true, true, glsp.NewError(glsp.ErrorCodeServerNotInitialized, "server not initialized")
 	}
 
 	switch context.Method {
 	// Base Protocol
 
 	case MethodCancelRequest:
-		if self.CancelRequest != nil {
-			validMethod = true
-			var params CancelParams
-			if err = json.Unmarshal(context.Params, &params); err == nil {
-				validParams = true
+		// Always handled, because the server cancels the request itself
+		validMethod = true
+		var params CancelParams
+		if err = json.Unmarshal(context.Params, &params); err == nil {
+			validParams = true
+			if self.CancelRequest != nil {
 				err = self.CancelRequest(context, &params)
 			}
 		}
@@ -126,6 +127,7 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 			var params InitializeParams
 			if err = json.Unmarshal(context.Params, &params); err == nil {
 				validParams = true
+				SetInitializeParams(context, &params)
 				if r, err = self.Initialize(context, &params); err == nil {
 					self.SetInitialized(true)
 				}
@@ -181,11 +183,13 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 	// Window
 
 	case MethodWindowWorkDoneProgressCancel:
-		if self.WindowWorkDoneProgressCancel != nil {
-			validMethod = true
-			var params WorkDoneProgressCancelParams
-			if err = json.Unmarshal(context.Params, &params); err == nil {
-				validParams = true
+		// Always handled, for [ProgressReporter]
+		validMethod = true
+		var params WorkDoneProgressCancelParams
+		if err = json.Unmarshal(context.Params, &params); err == nil {
+			validParams = true
+			CancelWorkDoneProgress(context, params.Token)
+			if self.WindowWorkDoneProgressCancel != nil {
 				err = self.WindowWorkDoneProgressCancel(context, &params)
 			}
 		}
@@ -355,11 +359,13 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 		}
 
 	case MethodTextDocumentDidClose:
-		if self.TextDocumentDidClose != nil {
-			validMethod = true
-			var params DidCloseTextDocumentParams
-			if err = json.Unmarshal(context.Params, &params); err == nil {
-				validParams = true
+		// Always handled, for [SemanticTokensCache]
+		validMethod = true
+		var params DidCloseTextDocumentParams
+		if err = json.Unmarshal(context.Params, &params); err == nil {
+			validParams = true
+			self.SemanticTokensCache().Forget(params.TextDocument.URI)
+			if self.TextDocumentDidClose != nil {
 				err = self.TextDocumentDidClose(context, &params)
 			}
 		}
@@ -662,7 +668,18 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 			var params SemanticTokensParams
 			if err = json.Unmarshal(context.Params, &params); err == nil {
 				validParams = true
-				r, err = self.TextDocumentSemanticTokensFull(context, &params)
+				if self.TextDocumentSemanticTokensFullDelta == nil {
+					// Automatic delta support needs the full result, so we can't stream
+					params.PartialResultToken = nil
+				}
+				var tokens *SemanticTokens
+				if tokens, err = self.TextDocumentSemanticTokensFull(context, &params); err == nil {
+					if self.TextDocumentSemanticTokensFullDelta == nil {
+						// Automatic delta support
+						tokens = self.SemanticTokensCache().Full(params.TextDocument.URI, tokens)
+					}
+					r = tokens
+				}
 			}
 		}
 
@@ -674,6 +691,20 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 				validParams = true
 				r, err = self.TextDocumentSemanticTokensFullDelta(context, &params)
 			}
+		} else if self.TextDocumentSemanticTokensFull != nil {
+			// Automatic delta support
+			validMethod = true
+			var params SemanticTokensDeltaParams
+			if err = json.Unmarshal(context.Params, &params); err == nil {
+				validParams = true
+				var tokens *SemanticTokens
+				if tokens, err = self.TextDocumentSemanticTokensFull(context, &SemanticTokensParams{
+					WorkDoneProgressParams: params.WorkDoneProgressParams,
+					TextDocument:           params.TextDocument,
+				}); err == nil {
+					r = self.SemanticTokensCache().Delta(params.TextDocument.URI, params.PreviousResultID, tokens)
+				}
+			}
 		}
 
 	case MethodTextDocumentSemanticTokensRange:
@@ -725,6 +756,10 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 		}
 	}
 
+	if (err == nil) && (r != nil) {
+		r = DowngradeResult(context.Method, context.Params, r, GetClientCapabilities(context))
+	}
+
 	return
 }
 
@@ -740,6 +775,17 @@ func (self *Handler) SetInitialized(initialized bool) {
 	self.initialized = initialized
 }
 
+// Used for automatic delta support when TextDocumentSemanticTokensFull is set but
+// TextDocumentSemanticTokensFullDelta is not.
+func (self *Handler) SemanticTokensCache() *SemanticTokensCache {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.semanticTokens == nil {
+		self.semanticTokens = NewSemanticTokensCache()
+	}
+	return self.semanticTokens
+}
+
 func (self *Handler) CreateServerCapabilities() ServerCapabilities {
 	var capabilities ServerCapabilities
 
@@ -876,12 +922,9 @@ func (self *Handler) CreateServerCapabilities() ServerCapabilities {
 		if _, ok := capabilities.SemanticTokensProvider.(*SemanticTokensOptions); !ok {
 			capabilities.SemanticTokensProvider = &SemanticTokensOptions{}
 		}
-		if self.TextDocumentSemanticTokensFullDelta != nil {
-			capabilities.SemanticTokensProvider.(*SemanticTokensOptions).Full = &SemanticDelta{}
-			capabilities.SemanticTokensProvider.(*SemanticTokensOptions).Full.(*SemanticDelta).Delta = &True
-		} else {
-			capabilities.SemanticTokensProvider.(*SemanticTokensOptions).Full = true
-		}
+		// Delta is always supported, if not by a handler then automatically
+		capabilities.SemanticTokensProvider.(*SemanticTokensOptions).Full = &SemanticDelta{}
+		capabilities.SemanticTokensProvider.(*SemanticTokensOptions).Full.(*SemanticDelta).Delta = &True
 	}
 
 	if self.TextDocumentSemanticTokensRange != nil {
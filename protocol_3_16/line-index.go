@@ -0,0 +1,213 @@
+package protocol
+
+import (
+	"slices"
+	"sort"
+	"unicode/utf8"
+)
+
+//
+// LineIndex
+//
+
+// Converts between positions and byte offsets (indexes) in O(log n) time by keeping the
+// byte offset of every line. Build it once per content version.
+//
+// Like [Position.IndexIn], characters are by default counted in UTF-16 code units and a
+// character beyond the end of the line defaults back to the line length. Unlike [Position.IndexIn],
+// positions beyond the last line or beyond the end of the content are clamped to the end
+// of the content rather than returning 0.
+type LineIndex struct {
+	content    string
+	codeUnits  CodeUnitsFunc
+	lineStarts []int  // byte offsets
+	lineASCII  []bool // lines that are pure ASCII can be indexed directly
+}
+
+// Returns the number of code units in a rune (which takes width bytes in the content).
+// Note that all supported encodings use a single code unit for ASCII.
+type CodeUnitsFunc func(r rune, width int) int
+
+func UTF8CodeUnits(r rune, width int) int {
+	return width
+}
+
+// This is the default encoding for LSP.
+func UTF16CodeUnits(r rune, width int) int {
+	if r >= 0x10000 {
+		// A surrogate pair
+		return 2
+	} else {
+		return 1
+	}
+}
+
+func UTF32CodeUnits(r rune, width int) int {
+	return 1
+}
+
+// Counts characters in UTF-16 code units.
+func NewLineIndex(content string) *LineIndex {
+	return NewLineIndexWithCodeUnits(content, UTF16CodeUnits)
+}
+
+func NewLineIndexWithCodeUnits(content string, codeUnits CodeUnitsFunc) *LineIndex {
+	lineStarts, lineASCII := scanLines(content, 0, len(content), []int{0}, nil)
+	return &LineIndex{
+		content:    content,
+		codeUnits:  codeUnits,
+		lineStarts: lineStarts,
+		lineASCII:  lineASCII,
+	}
+}
+
+func (self *LineIndex) Content() string {
+	return self.content
+}
+
+func (self *LineIndex) LineCount() int {
+	return len(self.lineStarts)
+}
+
+// Returns the line's content, not including the "\n".
+func (self *LineIndex) Line(line UInteger) string {
+	start, end := self.lineBounds(int(line))
+	return self.content[start:end]
+}
+
+func (self *LineIndex) IndexOf(position Position) int {
+	line := int(position.Line)
+	if line >= len(self.lineStarts) {
+		return len(self.content)
+	}
+
+	start, end := self.lineBounds(line)
+	character := int(position.Character)
+
+	if self.lineASCII[line] {
+		if character > end-start {
+			return end
+		}
+		return start + character
+	}
+
+	index := start
+	for count := 0; (count < character) && (index < end); {
+		r, width := utf8.DecodeRuneInString(self.content[index:end])
+		units := self.codeUnits(r, width)
+		if count+units > character {
+			// We finished in the middle of the rune, so do not advance past it
+			break
+		}
+		count += units
+		index += width
+	}
+
+	return index
+}
+
+func (self *LineIndex) IndexesOf(range_ Range) (int, int) {
+	return self.IndexOf(range_.Start), self.IndexOf(range_.End)
+}
+
+// Index is clamped to the content. If it is in the middle of a UTF-8 sequence the
+// position will be that of the beginning of the sequence.
+func (self *LineIndex) PositionOf(index int) Position {
+	if index < 0 {
+		index = 0
+	} else if index > len(self.content) {
+		index = len(self.content)
+	}
+
+	line := self.lineOf(index)
+
+	start := self.lineStarts[line]
+	if self.lineASCII[line] {
+		return Position{Line: UInteger(line), Character: UInteger(index - start)}
+	}
+
+	var character int
+	for offset := start; offset < index; {
+		r, width := utf8.DecodeRuneInString(self.content[offset:])
+		if offset+width > index {
+			break
+		}
+		character += self.codeUnits(r, width)
+		offset += width
+	}
+
+	return Position{Line: UInteger(line), Character: UInteger(character)}
+}
+
+func (self *LineIndex) RangeOf(start int, end int) Range {
+	return Range{Start: self.PositionOf(start), End: self.PositionOf(end)}
+}
+
+// Replaces the bytes between start and end with the text, updating the index in place.
+// Only the lines touched by the replacement are scanned; the starts of the lines after it
+// are shifted.
+func (self *LineIndex) replace(start int, end int, text string) {
+	firstLine := self.lineOf(start)
+	lastLine := self.lineOf(end)
+	_, lastLineEnd := self.lineBounds(lastLine)
+	delta := len(text) - (end - start)
+
+	self.content = self.content[:start] + text + self.content[end:]
+
+	lineStarts, lineASCII := scanLines(self.content, self.lineStarts[firstLine], lastLineEnd+delta, []int{self.lineStarts[firstLine]}, nil)
+
+	for line := lastLine + 1; line < len(self.lineStarts); line++ {
+		self.lineStarts[line] += delta
+	}
+
+	self.lineStarts = slices.Replace(self.lineStarts, firstLine, lastLine+1, lineStarts...)
+	self.lineASCII = slices.Replace(self.lineASCII, firstLine, lastLine+1, lineASCII...)
+}
+
+func (self *LineIndex) clone() *LineIndex {
+	return &LineIndex{
+		content:    self.content,
+		codeUnits:  self.codeUnits,
+		lineStarts: slices.Clone(self.lineStarts),
+		lineASCII:  slices.Clone(self.lineASCII),
+	}
+}
+
+// Returns the line that contains the byte offset
+func (self *LineIndex) lineOf(index int) int {
+	return sort.Search(len(self.lineStarts), func(line int) bool {
+		return self.lineStarts[line] > index
+	}) - 1
+}
+
+// Returns the byte offsets of the line's start and end (not including the "\n")
+func (self *LineIndex) lineBounds(line int) (int, int) {
+	if line >= len(self.lineStarts) {
+		return len(self.content), len(self.content)
+	}
+
+	start := self.lineStarts[line]
+	if line+1 < len(self.lineStarts) {
+		return start, self.lineStarts[line+1] - 1
+	} else {
+		return start, len(self.content)
+	}
+}
+
+// Appends the lines between the byte offsets, where the first line starts at from (its
+// start must already be in lineStarts) and the last line ends at to.
+func scanLines(content string, from int, to int, lineStarts []int, lineASCII []bool) ([]int, []bool) {
+	ascii := true
+	for index := from; index < to; index++ {
+		switch c := content[index]; {
+		case c == '\n':
+			lineASCII = append(lineASCII, ascii)
+			lineStarts = append(lineStarts, index+1)
+			ascii = true
+
+		case c >= utf8.RuneSelf:
+			ascii = false
+		}
+	}
+	return lineStarts, append(lineASCII, ascii)
+}
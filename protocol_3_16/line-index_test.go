@@ -0,0 +1,207 @@
+package protocol
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var lineIndexContents = []string{
+	"",
+	"hello",
+	"hello\nworld\n",
+	"a\n\nb",
+	"line one\r\nline two\r\n",
+	"ünïcödé\nascii\n日本語",
+	"😀 emoji\na😀b😀c\n😀",
+	"\n\n\n",
+}
+
+func TestLineIndexIndexOf(t *testing.T) {
+	for _, content := range lineIndexContents {
+		index := NewLineIndex(content)
+		lines := strings.Split(content, "\n")
+
+		for line, text := range lines {
+			last := line == len(lines)-1
+			for character := 0; character <= len(text)+3; character++ {
+				position := Position{Line: UInteger(line), Character: UInteger(character)}
+				if last && (character > utf16Length(text)) {
+					// IndexIn returns 0 beyond the end of the content, while we clamp
+					if got := index.IndexOf(position); got != len(content) {
+						t.Errorf("%q %v: got %d, expected %d", content, position, got, len(content))
+					}
+					continue
+				}
+
+				if got, expected := index.IndexOf(position), position.IndexIn(content); got != expected {
+					t.Errorf("%q %v: got %d, expected %d", content, position, got, expected)
+				}
+			}
+		}
+
+		// Beyond the last line
+		if got := index.IndexOf(Position{Line: UInteger(len(lines) + 1)}); got != len(content) {
+			t.Errorf("%q: beyond the last line got %d, expected %d", content, got, len(content))
+		}
+	}
+}
+
+func TestLineIndexPositionOf(t *testing.T) {
+	for _, content := range lineIndexContents {
+		index := NewLineIndex(content)
+		for offset := 0; offset <= len(content); offset++ {
+			if !utf8.RuneStart(safeByte(content, offset)) {
+				continue
+			}
+			position := index.PositionOf(offset)
+			if back := index.IndexOf(position); back != offset {
+				t.Errorf("%q: %d -> %v -> %d", content, offset, position, back)
+			}
+		}
+	}
+}
+
+func TestLineIndexCodeUnits(t *testing.T) {
+	content := "a😀b\n"
+	tests := []struct {
+		codeUnits CodeUnitsFunc
+		character UInteger
+		index     int
+	}{
+		{UTF8CodeUnits, 5, 5},
+		{UTF16CodeUnits, 3, 5},
+		{UTF16CodeUnits, 2, 1}, // in the middle of the surrogate pair
+		{UTF32CodeUnits, 2, 5},
+	}
+
+	for _, test := range tests {
+		if got := NewLineIndexWithCodeUnits(content, test.codeUnits).IndexOf(Position{Character: test.character}); got != test.index {
+			t.Errorf("character %d: got %d, expected %d", test.character, got, test.index)
+		}
+	}
+}
+
+func TestApplyContentChanges(t *testing.T) {
+	tests := []struct {
+		content  string
+		changes  []any
+		expected string
+	}{
+		{"hello world", []any{TextDocumentContentChangeEvent{Range: &Range{Start: Position{0, 6}, End: Position{0, 11}}, Text: "there"}}, "hello there"},
+		{"a😀b", []any{TextDocumentContentChangeEvent{Range: &Range{Start: Position{0, 3}, End: Position{0, 4}}, Text: "c"}}, "a😀c"},
+		{"one\ntwo\n", []any{
+			TextDocumentContentChangeEvent{Range: &Range{Start: Position{0, 3}, End: Position{1, 0}}, Text: " "},
+			&TextDocumentContentChangeEvent{Range: &Range{Start: Position{0, 0}, End: Position{0, 0}}, Text: "\n"},
+		}, "\none two\n"},
+		{"short\n", []any{TextDocumentContentChangeEvent{Range: &Range{Start: Position{0, 100}, End: Position{0, 100}}, Text: "!"}}, "short!\n"},
+		{"old", []any{
+			TextDocumentContentChangeEventWhole{Text: "new\n"},
+			TextDocumentContentChangeEvent{Range: &Range{Start: Position{1, 0}, End: Position{1, 0}}, Text: "line"},
+		}, "new\nline"},
+	}
+
+	for _, test := range tests {
+		if content, err := ApplyContentChanges(test.content, test.changes); err != nil {
+			t.Errorf("%q: %s", test.content, err.Error())
+		} else if content != test.expected {
+			t.Errorf("%q: got %q, expected %q", test.content, content, test.expected)
+		}
+	}
+}
+
+func TestLineIndexIncremental(t *testing.T) {
+	alphabet := []string{"a", "b", " ", "\n", "\r\n", "é", "😀"}
+	random := rand.New(rand.NewSource(1))
+	randomText := func(length int) string {
+		var builder strings.Builder
+		for index := 0; index < length; index++ {
+			builder.WriteString(alphabet[random.Intn(len(alphabet))])
+		}
+		return builder.String()
+	}
+
+	for iteration := 0; iteration < 500; iteration++ {
+		content := randomText(random.Intn(40))
+		index := NewLineIndex(content)
+
+		var changes []any
+		for count := random.Intn(5) + 1; count > 0; count-- {
+			start := Position{Line: UInteger(random.Intn(8)), Character: UInteger(random.Intn(8))}
+			end := Position{Line: start.Line + UInteger(random.Intn(2)), Character: UInteger(random.Intn(8))}
+			if (end.Line == start.Line) && (end.Character < start.Character) {
+				end.Character = start.Character
+			}
+			changes = append(changes, TextDocumentContentChangeEvent{Range: &Range{Start: start, End: end}, Text: randomText(random.Intn(6))})
+		}
+
+		content_, index_, err := applyContentChanges(content, index, changes, UTF16CodeUnits)
+		if err != nil {
+			// Ranges can be inverted after clamping
+			continue
+		}
+
+		expected, err := ApplyContentChanges(content, changes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content_ != expected {
+			t.Fatalf("%q: got %q, expected %q", content, content_, expected)
+		}
+
+		fresh := NewLineIndex(content_)
+		if (index_.content != fresh.content) || !reflect.DeepEqual(index_.lineStarts, fresh.lineStarts) || !reflect.DeepEqual(index_.lineASCII, fresh.lineASCII) {
+			t.Fatalf("%q %v: incremental index differs from a fresh one", content, changes)
+		}
+
+		// The original index must not be modified
+		if original := NewLineIndex(content); !reflect.DeepEqual(index.lineStarts, original.lineStarts) || !reflect.DeepEqual(index.lineASCII, original.lineASCII) {
+			t.Fatalf("%q: original index was modified", content)
+		}
+	}
+}
+
+func TestDocumentsDidChange(t *testing.T) {
+	documents := NewDocuments()
+	uri := DocumentUri("file:///test.txt")
+	documents.DidOpen(&DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, Version: 1, Text: "one\ntwo"}})
+	before, _ := documents.Get(uri)
+	beforeIndex := before.LineIndex()
+
+	if err := documents.DidChange(&DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{TextDocumentIdentifier: TextDocumentIdentifier{URI: uri}, Version: 2},
+		ContentChanges: []any{TextDocumentContentChangeEvent{Range: &Range{Start: Position{0, 3}, End: Position{0, 3}}, Text: "\nand a half"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	after, _ := documents.Get(uri)
+	if after.Content != "one\nand a half\ntwo" {
+		t.Errorf("got %q", after.Content)
+	}
+	if got := after.LineIndex().Line(2); got != "two" {
+		t.Errorf("line 2 is %q", got)
+	}
+
+	// The previous snapshot is unchanged
+	if (beforeIndex.LineCount() != 2) || (beforeIndex.Line(1) != "two") {
+		t.Error("previous snapshot was modified")
+	}
+}
+
+func utf16Length(text string) int {
+	length := 0
+	for _, r := range text {
+		length += UTF16CodeUnits(r, 0)
+	}
+	return length
+}
+
+func safeByte(content string, offset int) byte {
+	if offset < len(content) {
+		return content[offset]
+	}
+	return 0
+}
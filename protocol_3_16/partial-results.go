@@ -0,0 +1,114 @@
+package protocol
+
+import (
+	contextpkg "context"
+	"sync"
+
+	"github.com/tliron/glsp"
+)
+
+//
+// PartialResultWriter
+//
+
+// Streams the result of a request to the client in chunks via "$/progress", using the
+// partial result token provided by the client. If the client did not provide a token then
+// the chunks are buffered instead.
+//
+// Either way the handler should return Result, because the spec requires the final response
+// to be empty if partial results were sent.
+type PartialResultWriter[T any] struct {
+	// Nil if the client did not provide a token, in which case we are buffering
+	Token *ProgressToken
+
+	context *glsp.Context
+	wrap    func(chunk []T) any
+	buffer  []T
+	lock    sync.Mutex
+}
+
+// Chunks are sent as []T.
+func NewPartialResultWriter[T any](context *glsp.Context, params PartialResultParams) *PartialResultWriter[T] {
+	return NewPartialResultWriterWithWrapper[T](context, params, nil)
+}
+
+// The wrapper converts chunks to the partial result type of the request. If it is nil then
+// chunks are sent as []T.
+func NewPartialResultWriterWithWrapper[T any](context *glsp.Context, params PartialResultParams, wrap func(chunk []T) any) *PartialResultWriter[T] {
+	return &PartialResultWriter[T]{
+		Token:   params.PartialResultToken,
+		context: context,
+		wrap:    wrap,
+	}
+}
+
+// For "textDocument/references", etc.
+func NewLocationsPartialResultWriter(context *glsp.Context, params PartialResultParams) *PartialResultWriter[Location] {
+	return NewPartialResultWriter[Location](context, params)
+}
+
+// For "workspace/symbol".
+func NewSymbolInformationPartialResultWriter(context *glsp.Context, params PartialResultParams) *PartialResultWriter[SymbolInformation] {
+	return NewPartialResultWriter[SymbolInformation](context, params)
+}
+
+// For "textDocument/semanticTokens/full" and "textDocument/semanticTokens/range". Chunks must
+// be whole tokens in order, as they are concatenated by the client.
+//
+// Note that when [Handler] provides automatic delta support for
+// "textDocument/semanticTokens/full" it removes the partial result token, because it needs
+// the full result.
+func NewSemanticTokensPartialResultWriter(context *glsp.Context, params PartialResultParams) *PartialResultWriter[UInteger] {
+	return NewPartialResultWriterWithWrapper(context, params, func(chunk []UInteger) any {
+		return SemanticTokensPartialResult{Data: chunk}
+	})
+}
+
+// Whether chunks are sent to the client rather than buffered.
+func (self *PartialResultWriter[T]) Streaming() bool {
+	return self.Token != nil
+}
+
+// Sends the chunk to the client, or buffers it if there is no token. Sending stops with an
+// error if the request is cancelled.
+func (self *PartialResultWriter[T]) Write(chunk ...T) error {
+	if len(chunk) == 0 {
+		return nil
+	}
+
+	if self.Token == nil {
+		self.lock.Lock()
+		defer self.lock.Unlock()
+		self.buffer = append(self.buffer, chunk...)
+		return nil
+	}
+
+	var value any
+	if self.wrap != nil {
+		value = self.wrap(chunk)
+	} else {
+		value = chunk
+	}
+
+	context := self.context.Context
+	if context == nil {
+		context = contextpkg.Background()
+	}
+
+	return self.context.NotifyWithContext(context, MethodProgress, &ProgressParams{
+		Token: *self.Token,
+		Value: value,
+	})
+}
+
+// The final result for the response: the buffered chunks, or an empty (but not nil) slice
+// if we are streaming.
+func (self *PartialResultWriter[T]) Result() []T {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.buffer == nil {
+		return []T{}
+	}
+	return self.buffer
+}
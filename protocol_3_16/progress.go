@@ -0,0 +1,284 @@
+package protocol
+
+import (
+	contextpkg "context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tliron/glsp"
+)
+
+// The default minimum interval between reports.
+const DefaultProgressInterval = 100 * time.Millisecond
+
+// How long to wait for the client to respond to "window/workDoneProgress/create".
+const ProgressCreateTimeout = 5 * time.Second
+
+var progressTokenCount uint64
+
+//
+// ProgressReporter
+//
+
+// Reports work-done progress to the client via "$/progress".
+//
+// If the client cancels the progress (via "window/workDoneProgress/cancel", which is handled
+// by [Handler]), the reporter's Context is cancelled.
+//
+// If there is no token to report with then the reporter does nothing, so it can always be
+// used unconditionally.
+type ProgressReporter struct {
+	// Nil if there is no token to report with
+	Token *ProgressToken
+
+	// Reports that come faster than this are delayed until the interval ends, and then
+	// only the latest of them is sent. Zero means no throttling.
+	Interval time.Duration
+
+	context     contextpkg.Context
+	cancel      contextpkg.CancelFunc
+	client      *Client
+	glspContext *glsp.Context
+	lastReport  time.Time
+	pending     *WorkDoneProgressReport
+	timer       *time.Timer
+	ended       bool
+	lock        sync.Mutex
+	sendLock    sync.Mutex // so that a delayed report is never sent after the end
+}
+
+// Uses the workDoneToken if provided by the client (from [WorkDoneProgressParams]), otherwise
+// if the client supports it creates a new token via "window/workDoneProgress/create". The
+// workDoneToken can be nil.
+//
+// Creating a token waits for the client's response (at most [ProgressCreateTimeout], after
+// which an error is returned). This is safe in any handler, including notification handlers,
+// because the server handles messages off the connection's read loop. Note that unless the
+// server is concurrent, the session's later messages are handled only after the handler
+// returns, so long-running work should happen in a goroutine.
+//
+// The reporter's context is derived from the glsp.Context's Context (if not nil), so that
+// cancelling the request would also cancel the reporter.
+func NewProgressReporter(context *glsp.Context, workDoneToken *ProgressToken) (*ProgressReporter, error) {
+	var parent contextpkg.Context
+	if context.Context != nil {
+		parent = context.Context
+	} else {
+		parent = contextpkg.Background()
+	}
+
+	self := ProgressReporter{
+		Token:       workDoneToken,
+		Interval:    DefaultProgressInterval,
+		client:      NewClient(context),
+		glspContext: context,
+	}
+	self.context, self.cancel = contextpkg.WithCancel(parent)
+
+	if (self.Token == nil) && GetClientCapabilities(context).SupportsWorkDoneProgress() {
+		token := ProgressToken{Value: fmt.Sprintf("glsp-progress-%d", atomic.AddUint64(&progressTokenCount, 1))}
+		createContext, cancelCreate := contextpkg.WithTimeout(self.context, ProgressCreateTimeout)
+		err := self.client.WorkDoneProgressCreate(createContext, &WorkDoneProgressCreateParams{Token: token})
+		cancelCreate()
+		if err == nil {
+			self.Token = &token
+		} else {
+			self.cancel()
+			return nil, err
+		}
+	}
+
+	if self.Token != nil {
+		getProgressReporters(context).add(*self.Token, self.cancel)
+	}
+
+	return &self, nil
+}
+
+// Cancelled when the client cancels the progress or after End.
+func (self *ProgressReporter) Context() contextpkg.Context {
+	return self.context
+}
+
+// The percentage is from 0 to 100. If it is nil the progress is indeterminate, in which
+// case reports should not have a percentage either.
+func (self *ProgressReporter) Begin(title string, message string, percentage *UInteger, cancellable bool) error {
+	begin := WorkDoneProgressBegin{
+		Kind:       "begin",
+		Title:      title,
+		Percentage: percentage,
+	}
+	if message != "" {
+		begin.Message = &message
+	}
+	if cancellable {
+		begin.Cancellable = &cancellable
+	}
+
+	self.lock.Lock()
+	self.lastReport = time.Now()
+	self.lock.Unlock()
+
+	return self.progress(begin)
+}
+
+// The percentage is from 0 to 100 and should not decrease. It should be nil if the progress
+// is indeterminate. An empty message leaves the previous message in place.
+//
+// Will be delayed if it comes too soon after the previous report (see Interval), and then
+// replaced if another report comes before it is sent. A delayed report is sent before the
+// end. Reports after the end are ignored.
+func (self *ProgressReporter) Report(percentage *UInteger, message string) error {
+	report := WorkDoneProgressReport{
+		Kind:       "report",
+		Percentage: percentage,
+	}
+	if message != "" {
+		report.Message = &message
+	}
+
+	self.lock.Lock()
+	if self.ended {
+		self.lock.Unlock()
+		return nil
+	}
+	if self.Interval > 0 {
+		if wait := self.Interval - time.Since(self.lastReport); wait > 0 {
+			self.pending = &report
+			if self.timer == nil {
+				self.timer = time.AfterFunc(wait, func() {
+					self.sendLock.Lock()
+					defer self.sendLock.Unlock()
+					self.flush()
+				})
+			}
+			self.lock.Unlock()
+			return nil
+		}
+	}
+	self.lastReport = time.Now()
+	self.pending = nil // superseded
+	self.lock.Unlock()
+
+	self.sendLock.Lock()
+	defer self.sendLock.Unlock()
+	return self.progress(report)
+}
+
+// An empty message means no final message. Also cancels the reporter's context.
+func (self *ProgressReporter) End(message string) error {
+	if self.Token != nil {
+		getProgressReporters(self.glspContext).remove(*self.Token)
+	}
+	defer self.cancel()
+
+	self.lock.Lock()
+	self.ended = true
+	self.lock.Unlock()
+
+	self.sendLock.Lock()
+	defer self.sendLock.Unlock()
+
+	if err := self.flush(); err != nil {
+		return err
+	}
+
+	end := WorkDoneProgressEnd{
+		Kind: "end",
+	}
+	if message != "" {
+		end.Message = &message
+	}
+
+	return self.progress(end)
+}
+
+// Sends the delayed report, if there is one. Call while send-locked.
+func (self *ProgressReporter) flush() error {
+	self.lock.Lock()
+	if self.timer != nil {
+		self.timer.Stop()
+		self.timer = nil
+	}
+	report := self.pending
+	self.pending = nil
+	if report != nil {
+		self.lastReport = time.Now()
+	}
+	self.lock.Unlock()
+
+	if report != nil {
+		return self.progress(*report)
+	}
+	return nil
+}
+
+func (self *ProgressReporter) progress(value any) error {
+	if self.Token == nil {
+		return nil
+	}
+
+	// Note that we do not use our own context, because we want to be able to report
+	// the end even if it was cancelled
+	return self.client.Progress(contextpkg.Background(), &ProgressParams{
+		Token: *self.Token,
+		Value: value,
+	})
+}
+
+// Cancels the context of the [ProgressReporter] with the token, if there is one. This is
+// called automatically by [Handler] for "window/workDoneProgress/cancel".
+func CancelWorkDoneProgress(context *glsp.Context, token ProgressToken) {
+	getProgressReporters(context).cancel(token)
+}
+
+//
+// progressReporters
+//
+
+type progressReportersKey struct{}
+
+// Active reporters per connection, so that we can cancel them
+type progressReporters struct {
+	cancels map[string]contextpkg.CancelFunc
+	lock    sync.Mutex
+}
+
+func getProgressReporters(context *glsp.Context) *progressReporters {
+	if context.State == nil {
+		// Cancellation will not be supported
+		return &progressReporters{cancels: make(map[string]contextpkg.CancelFunc)}
+	}
+
+	return context.State.GetOrSet(progressReportersKey{}, &progressReporters{cancels: make(map[string]contextpkg.CancelFunc)}).(*progressReporters)
+}
+
+func (self *progressReporters) add(token ProgressToken, cancel contextpkg.CancelFunc) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.cancels[progressTokenKey(token)] = cancel
+}
+
+func (self *progressReporters) remove(token ProgressToken) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	delete(self.cancels, progressTokenKey(token))
+}
+
+func (self *progressReporters) cancel(token ProgressToken) {
+	self.lock.Lock()
+	cancel, ok := self.cancels[progressTokenKey(token)]
+	delete(self.cancels, progressTokenKey(token))
+	self.lock.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+// Integer and string tokens are distinct
+func progressTokenKey(token ProgressToken) string {
+	return fmt.Sprintf("%T:%v", token.Value, token.Value)
+}
@@ -0,0 +1,78 @@
+package protocol
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/tliron/glsp"
+)
+
+func TestProgressReporterFromRequest(t *testing.T) {
+	methods := make(chan string, 10)
+
+	handler := Handler{
+		Initialize: func(context *glsp.Context, params *InitializeParams) (any, error) {
+			return InitializeResult{}, nil
+		},
+		TextDocumentHover: func(context *glsp.Context, params *HoverParams) (*Hover, error) {
+			// Creates a token, because the client did not provide one
+			if progressReporter, err := NewProgressReporter(context, nil); err == nil {
+				if progressReporter.Token == nil {
+					t.Error("no token")
+				}
+				if err := progressReporter.Begin("test", "", nil, false); err != nil {
+					return nil, err
+				}
+				if err := progressReporter.End("done"); err != nil {
+					return nil, err
+				}
+			} else {
+				return nil, err
+			}
+			return nil, nil
+		},
+	}
+
+	client := connect(t, &handler, func(method string, params json.RawMessage) any {
+		methods <- method
+		return nil
+	})
+
+	var capabilities ClientCapabilities
+	if err := json.Unmarshal([]byte(`{"window":{"workDoneProgress":true}}`), &capabilities); err != nil {
+		t.Fatal(err)
+	}
+	initialize(t, client, capabilities)
+
+	done := make(chan error, 1)
+	go func() {
+		context, cancel := contextpkg.WithTimeout(contextpkg.Background(), testTimeout)
+		defer cancel()
+		done <- client.Call(context, string(MethodTextDocumentHover), &HoverParams{}, nil)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timed out")
+	}
+
+	// The notifications may arrive after the response
+	var got []string
+	for len(got) < 3 {
+		select {
+		case method := <-methods:
+			got = append(got, method)
+		case <-time.After(testTimeout):
+			t.Fatalf("timed out: %v", got)
+		}
+	}
+	if (got[0] != string(ServerWindowWorkDoneProgressCreate)) || (got[1] != string(MethodProgress)) || (got[2] != string(MethodProgress)) {
+		t.Errorf("unexpected messages: %v", got)
+	}
+}
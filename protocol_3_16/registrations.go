@@ -0,0 +1,291 @@
+package protocol
+
+import (
+	contextpkg "context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/tliron/glsp"
+)
+
+//
+// RegisteredCapability
+//
+
+type RegisteredCapability struct {
+	ID              string
+	Method          Method
+	RegisterOptions any
+
+	// False if the client does not support dynamic registration for the method, in which
+	// case the registration is only tracked by us and the capability must be provided
+	// statically (see [RegistrationManager])
+	Dynamic bool
+}
+
+//
+// RegistrationManager
+//
+
+// Tracks capabilities registered with "client/registerCapability".
+//
+// If the client does not support dynamic registration for a method then registration falls
+// back to static: nothing is sent to the client and the capability must instead be in the
+// [ServerCapabilities] returned from "initialize" (which [Handler.CreateServerCapabilities]
+// does for every method with a handler). Handlers can then use [RegistrationManager.IsRegistered]
+// to decide whether the feature is currently enabled.
+//
+// Conversely, a capability that is registered dynamically must not also be provided statically.
+// Use [RegistrationManager.RemoveDynamicCapabilities] on the static capabilities before returning them.
+type RegistrationManager struct {
+	client        *Client
+	context       *glsp.Context
+	registrations map[string]*RegisteredCapability
+	nextID        uint64
+	lock          sync.Mutex
+}
+
+// The context should be that of a message received after "initialize" (or of "initialize"
+// itself), so that the client capabilities are available. It is used for sending requests
+// to the client and can be kept after the handler returns.
+func NewRegistrationManager(context *glsp.Context) *RegistrationManager {
+	return &RegistrationManager{
+		client:        NewClient(context),
+		context:       context,
+		registrations: make(map[string]*RegisteredCapability),
+	}
+}
+
+func (self *RegistrationManager) SupportsDynamic(method Method) bool {
+	return GetClientCapabilities(self.context).SupportsDynamicRegistration(method)
+}
+
+// Returns the registration ID.
+//
+// Waits for the client's response, so it is safe to call from any handler, including
+// notification handlers such as "initialized" and "workspace/didChangeConfiguration" (the
+// server handles messages off the connection's read loop). Note that unless the server is
+// concurrent, the session's later messages are handled only after the handler returns.
+//
+// Returns an error if the client does not support dynamic registration for a method that
+// cannot be provided statically (e.g. "workspace/didChangeWatchedFiles"), because the
+// client would never use it.
+func (self *RegistrationManager) Register(context contextpkg.Context, method Method, registerOptions any) (string, error) {
+	dynamic := self.SupportsDynamic(method)
+	if !dynamic && !hasStaticCapability(method) {
+		return "", fmt.Errorf("client does not support dynamic registration for %s, which has no static capability", method)
+	}
+
+	registration := RegisteredCapability{
+		ID:              self.newID(),
+		Method:          method,
+		RegisterOptions: registerOptions,
+		Dynamic:         dynamic,
+	}
+
+	if registration.Dynamic {
+		if err := self.client.RegisterCapability(context, &RegistrationParams{
+			Registrations: []Registration{{
+				ID:              registration.ID,
+				Method:          method,
+				RegisterOptions: registerOptions,
+			}},
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.registrations[registration.ID] = &registration
+
+	return registration.ID, nil
+}
+
+func (self *RegistrationManager) Unregister(context contextpkg.Context, id string) error {
+	self.lock.Lock()
+	registration, ok := self.registrations[id]
+	self.lock.Unlock()
+
+	if !ok {
+		return fmt.Errorf("capability not registered: %s", id)
+	}
+
+	return self.unregister(context, []*RegisteredCapability{registration})
+}
+
+// Unregisters all registrations for the method.
+func (self *RegistrationManager) UnregisterMethod(context contextpkg.Context, method Method) error {
+	var registrations []*RegisteredCapability
+	self.lock.Lock()
+	for _, registration := range self.registrations {
+		if registration.Method == method {
+			registrations = append(registrations, registration)
+		}
+	}
+	self.lock.Unlock()
+
+	return self.unregister(context, registrations)
+}
+
+// Returns the current registrations in order of registration.
+func (self *RegistrationManager) Registered() []RegisteredCapability {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	registrations := make([]RegisteredCapability, 0, len(self.registrations))
+	for _, registration := range self.registrations {
+		registrations = append(registrations, *registration)
+	}
+
+	sort.Slice(registrations, func(i int, j int) bool {
+		a, _ := strconv.ParseUint(registrations[i].ID, 10, 64)
+		b, _ := strconv.ParseUint(registrations[j].ID, 10, 64)
+		return a < b
+	})
+
+	return registrations
+}
+
+// Whether the method has at least one registration, dynamic or static.
+func (self *RegistrationManager) IsRegistered(method Method) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for _, registration := range self.registrations {
+		if registration.Method == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Removes from the static capabilities those for the methods that the client supports
+// registering dynamically. Methods that have no static capability (e.g.
+// "workspace/didChangeWatchedFiles") and text document synchronization methods are ignored.
+func (self *RegistrationManager) RemoveDynamicCapabilities(capabilities *ServerCapabilities, methods ...Method) {
+	for _, method := range methods {
+		if !self.SupportsDynamic(method) {
+			continue
+		}
+
+		switch method {
+		case MethodTextDocumentCompletion:
+			capabilities.CompletionProvider = nil
+		case MethodTextDocumentHover:
+			capabilities.HoverProvider = nil
+		case MethodTextDocumentSignatureHelp:
+			capabilities.SignatureHelpProvider = nil
+		case MethodTextDocumentDeclaration:
+			capabilities.DeclarationProvider = nil
+		case MethodTextDocumentDefinition:
+			capabilities.DefinitionProvider = nil
+		case MethodTextDocumentTypeDefinition:
+			capabilities.TypeDefinitionProvider = nil
+		case MethodTextDocumentImplementation:
+			capabilities.ImplementationProvider = nil
+		case MethodTextDocumentReferences:
+			capabilities.ReferencesProvider = nil
+		case MethodTextDocumentDocumentHighlight:
+			capabilities.DocumentHighlightProvider = nil
+		case MethodTextDocumentDocumentSymbol:
+			capabilities.DocumentSymbolProvider = nil
+		case MethodTextDocumentCodeAction:
+			capabilities.CodeActionProvider = nil
+		case MethodTextDocumentCodeLens:
+			capabilities.CodeLensProvider = nil
+		case MethodTextDocumentDocumentLink:
+			capabilities.DocumentLinkProvider = nil
+		case MethodTextDocumentColor:
+			capabilities.ColorProvider = nil
+		case MethodTextDocumentFormatting:
+			capabilities.DocumentFormattingProvider = nil
+		case MethodTextDocumentRangeFormatting:
+			capabilities.DocumentRangeFormattingProvider = nil
+		case MethodTextDocumentOnTypeFormatting:
+			capabilities.DocumentOnTypeFormattingProvider = nil
+		case MethodTextDocumentRename:
+			capabilities.RenameProvider = nil
+		case MethodTextDocumentFoldingRange:
+			capabilities.FoldingRangeProvider = nil
+		case MethodTextDocumentSelectionRange:
+			capabilities.SelectionRangeProvider = nil
+		case MethodTextDocumentLinkedEditingRange:
+			capabilities.LinkedEditingRangeProvider = nil
+		case MethodTextDocumentPrepareCallHierarchy:
+			capabilities.CallHierarchyProvider = nil
+		case MethodTextDocumentSemanticTokensFull, MethodTextDocumentSemanticTokensFullDelta, MethodTextDocumentSemanticTokensRange:
+			capabilities.SemanticTokensProvider = nil
+		case MethodTextDocumentMoniker:
+			capabilities.MonikerProvider = nil
+		case MethodWorkspaceSymbol:
+			capabilities.WorkspaceSymbolProvider = nil
+		case MethodWorkspaceExecuteCommand:
+			capabilities.ExecuteCommandProvider = nil
+		}
+
+		if (capabilities.Workspace != nil) && (capabilities.Workspace.FileOperations != nil) {
+			fileOperations := capabilities.Workspace.FileOperations
+			switch method {
+			case MethodWorkspaceDidCreateFiles:
+				fileOperations.DidCreate = nil
+			case MethodWorkspaceWillCreateFiles:
+				fileOperations.WillCreate = nil
+			case MethodWorkspaceDidRenameFiles:
+				fileOperations.DidRename = nil
+			case MethodWorkspaceWillRenameFiles:
+				fileOperations.WillRename = nil
+			case MethodWorkspaceDidDeleteFiles:
+				fileOperations.DidDelete = nil
+			case MethodWorkspaceWillDeleteFiles:
+				fileOperations.WillDelete = nil
+			}
+		}
+	}
+}
+
+// Some methods can only be registered dynamically
+func hasStaticCapability(method Method) bool {
+	switch method {
+	case MethodWorkspaceDidChangeWatchedFiles, MethodWorkspaceDidChangeConfiguration:
+		return false
+	default:
+		return true
+	}
+}
+
+func (self *RegistrationManager) newID() string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.nextID++
+	return strconv.FormatUint(self.nextID, 10)
+}
+
+func (self *RegistrationManager) unregister(context contextpkg.Context, registrations []*RegisteredCapability) error {
+	var unregistrations []Unregistration
+	for _, registration := range registrations {
+		if registration.Dynamic {
+			unregistrations = append(unregistrations, Unregistration{
+				ID:     registration.ID,
+				Method: registration.Method,
+			})
+		}
+	}
+
+	if len(unregistrations) > 0 {
+		if err := self.client.UnregisterCapability(context, &UnregistrationParams{Unregisterations: unregistrations}); err != nil {
+			return err
+		}
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for _, registration := range registrations {
+		delete(self.registrations, registration.ID)
+	}
+
+	return nil
+}
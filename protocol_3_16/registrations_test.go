@@ -0,0 +1,77 @@
+package protocol
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/tliron/glsp"
+)
+
+func TestRegisterFromInitialized(t *testing.T) {
+	ids := make(chan string, 1)
+	errs := make(chan error, 1)
+	registrations := make(chan RegistrationParams, 1)
+
+	var registrationManager *RegistrationManager
+	handler := Handler{
+		Initialize: func(context *glsp.Context, params *InitializeParams) (any, error) {
+			return InitializeResult{}, nil
+		},
+		Initialized: func(context *glsp.Context, params *InitializedParams) error {
+			context_, cancel := contextpkg.WithTimeout(contextpkg.Background(), testTimeout)
+			defer cancel()
+
+			registrationManager = NewRegistrationManager(context)
+			if id, err := registrationManager.Register(context_, MethodWorkspaceDidChangeWatchedFiles, DidChangeWatchedFilesRegistrationOptions{
+				Watchers: []FileSystemWatcher{{GlobPattern: "**/*.go"}},
+			}); err == nil {
+				ids <- id
+			} else {
+				errs <- err
+			}
+			return nil
+		},
+	}
+
+	client := connect(t, &handler, func(method string, params json.RawMessage) any {
+		if method == string(ServerClientRegisterCapability) {
+			var registrationParams RegistrationParams
+			if err := json.Unmarshal(params, &registrationParams); err == nil {
+				registrations <- registrationParams
+			} else {
+				t.Error(err)
+			}
+		}
+		return nil
+	})
+
+	dynamicRegistration := true
+	initialize(t, client, ClientCapabilities{
+		Workspace: &WorkspaceClientCapabilities{
+			DidChangeWatchedFiles: &DidChangeWatchedFilesClientCapabilities{DynamicRegistration: &dynamicRegistration},
+		},
+	})
+
+	var registrationParams RegistrationParams
+	select {
+	case registrationParams = <-registrations:
+	case <-time.After(testTimeout):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case id := <-ids:
+		if (len(registrationParams.Registrations) != 1) || (registrationParams.Registrations[0].ID != id) || (registrationParams.Registrations[0].Method != string(MethodWorkspaceDidChangeWatchedFiles)) {
+			t.Errorf("unexpected registration: %+v", registrationParams)
+		}
+		if !registrationManager.IsRegistered(MethodWorkspaceDidChangeWatchedFiles) {
+			t.Errorf("not registered")
+		}
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(testTimeout):
+		t.Fatal("timed out")
+	}
+}
@@ -0,0 +1,122 @@
+package protocol
+
+import (
+	"strconv"
+	"sync"
+)
+
+//
+// SemanticTokensCache
+//
+
+// Keeps the last full semantic tokens result per document so that the
+// "textDocument/semanticTokens/full/delta" request can be answered with edits.
+//
+// Only the latest result ID of a document is valid. Older IDs are dropped, in which
+// case a full result is returned instead of a delta.
+type SemanticTokensCache struct {
+	results map[DocumentUri]*SemanticTokens
+	nextID  uint64
+	lock    sync.Mutex
+}
+
+func NewSemanticTokensCache() *SemanticTokensCache {
+	return &SemanticTokensCache{
+		results: make(map[DocumentUri]*SemanticTokens),
+	}
+}
+
+// Assigns a new result ID to the tokens and caches them. A nil result forgets the
+// document.
+func (self *SemanticTokensCache) Full(uri DocumentUri, tokens *SemanticTokens) *SemanticTokens {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if tokens == nil {
+		delete(self.results, uri)
+		return nil
+	}
+
+	return self.store(uri, tokens)
+}
+
+// Returns a [*SemanticTokensDelta] against the previous result if it is still cached,
+// otherwise a [*SemanticTokens] with the full data. Either way the tokens are cached
+// under a new result ID. A nil result forgets the document.
+func (self *SemanticTokensCache) Delta(uri DocumentUri, previousResultID string, tokens *SemanticTokens) any {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if tokens == nil {
+		delete(self.results, uri)
+		return nil
+	}
+
+	previous, ok := self.results[uri]
+	tokens = self.store(uri, tokens)
+
+	if ok && (previous.ResultID != nil) && (*previous.ResultID == previousResultID) {
+		return &SemanticTokensDelta{
+			ResultId: tokens.ResultID,
+			Edits:    DiffSemanticTokens(previous.Data, tokens.Data),
+		}
+	} else {
+		return tokens
+	}
+}
+
+func (self *SemanticTokensCache) Forget(uri DocumentUri) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	delete(self.results, uri)
+}
+
+// Call while locked.
+func (self *SemanticTokensCache) store(uri DocumentUri, tokens *SemanticTokens) *SemanticTokens {
+	self.nextID++
+	resultID := strconv.FormatUint(self.nextID, 10)
+
+	// We copy the data so that the caller can't modify our cached version
+	data := make([]UInteger, len(tokens.Data))
+	copy(data, tokens.Data)
+	self.results[uri] = &SemanticTokens{ResultID: &resultID, Data: data}
+
+	return &SemanticTokens{ResultID: &resultID, Data: tokens.Data}
+}
+
+// Computes the edits that transform the old data into the new data. Offsets refer to the
+// old data.
+//
+// The diff is computed per token (5 integers) so that edits never split a token. Note that
+// because of the relative encoding a change usually also modifies the token that follows it.
+func DiffSemanticTokens(old []UInteger, new []UInteger) []SemanticTokensEdit {
+	if (len(old)%5 != 0) || (len(new)%5 != 0) {
+		// Not valid token data, so diff per integer
+		return toSemanticTokensEdits(diffHunks(old, new), new, 1)
+	}
+
+	return toSemanticTokensEdits(diffHunks(toSemanticTokenTuples(old), toSemanticTokenTuples(new)), new, 5)
+}
+
+func toSemanticTokenTuples(data []UInteger) [][5]UInteger {
+	tuples := make([][5]UInteger, len(data)/5)
+	for index := range tuples {
+		copy(tuples[index][:], data[index*5:])
+	}
+	return tuples
+}
+
+func toSemanticTokensEdits(hunks []diffHunk, new []UInteger, size int) []SemanticTokensEdit {
+	edits := make([]SemanticTokensEdit, len(hunks))
+	for index, hunk := range hunks {
+		edits[index] = SemanticTokensEdit{
+			Start:       UInteger(hunk.aStart * size),
+			DeleteCount: UInteger((hunk.aEnd - hunk.aStart) * size),
+		}
+		if hunk.bEnd > hunk.bStart {
+			edits[index].Data = new[hunk.bStart*size : hunk.bEnd*size]
+		}
+	}
+	return edits
+}
@@ -0,0 +1,356 @@
+package protocol
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// The standard token types, in the order they are listed in the specification.
+var SemanticTokenTypes = []SemanticTokenType{
+	SemanticTokenTypeNamespace,
+	SemanticTokenTypeType,
+	SemanticTokenTypeClass,
+	SemanticTokenTypeEnum,
+	SemanticTokenTypeInterface,
+	SemanticTokenTypeStruct,
+	SemanticTokenTypeTypeParameter,
+	SemanticTokenTypeParameter,
+	SemanticTokenTypeVariable,
+	SemanticTokenTypeProperty,
+	SemanticTokenTypeEnumMember,
+	SemanticTokenTypeEvent,
+	SemanticTokenTypeFunction,
+	SemanticTokenTypeMethod,
+	SemanticTokenTypeMacro,
+	SemanticTokenTypeKeyword,
+	SemanticTokenTypeModifier,
+	SemanticTokenTypeComment,
+	SemanticTokenTypeString,
+	SemanticTokenTypeNumber,
+	SemanticTokenTypeRegexp,
+	SemanticTokenTypeOperator,
+}
+
+// The standard token modifiers, in the order they are listed in the specification.
+var SemanticTokenModifiers = []SemanticTokenModifier{
+	SemanticTokenModifierDeclaration,
+	SemanticTokenModifierDefinition,
+	SemanticTokenModifierReadonly,
+	SemanticTokenModifierStatic,
+	SemanticTokenModifierDeprecated,
+	SemanticTokenModifierAbstract,
+	SemanticTokenModifierAsync,
+	SemanticTokenModifierModification,
+	SemanticTokenModifierDocumentation,
+	SemanticTokenModifierDefaultLibrary,
+}
+
+func NewSemanticTokensLegend(types []SemanticTokenType, modifiers []SemanticTokenModifier) SemanticTokensLegend {
+	legend := SemanticTokensLegend{
+		TokenTypes:     make([]string, len(types)),
+		TokenModifiers: make([]string, len(modifiers)),
+	}
+	for index, type_ := range types {
+		legend.TokenTypes[index] = string(type_)
+	}
+	for index, modifier := range modifiers {
+		legend.TokenModifiers[index] = string(modifier)
+	}
+	return legend
+}
+
+// A legend with all the standard token types and modifiers.
+func NewDefaultSemanticTokensLegend() SemanticTokensLegend {
+	return NewSemanticTokensLegend(SemanticTokenTypes, SemanticTokenModifiers)
+}
+
+//
+// SemanticToken
+//
+
+// A token at an absolute position. The range must be on a single line unless the builder
+// has a [LineIndex].
+type SemanticToken struct {
+	Range     Range
+	Type      SemanticTokenType
+	Modifiers []SemanticTokenModifier
+}
+
+// ([fmt.Stringer] interface)
+func (self SemanticToken) String() string {
+	if len(self.Modifiers) > 0 {
+		modifiers := make([]string, len(self.Modifiers))
+		for index, modifier := range self.Modifiers {
+			modifiers[index] = string(modifier)
+		}
+		return fmt.Sprintf("%s %s [%s]", rangeString(self.Range), self.Type, strings.Join(modifiers, " "))
+	} else {
+		return fmt.Sprintf("%s %s", rangeString(self.Range), self.Type)
+	}
+}
+
+//
+// SemanticTokensBuilder
+//
+
+// Collects absolute tokens and encodes them into the relative format against a legend.
+//
+// Characters are counted in the same code units as the ranges of the added tokens (by
+// default UTF-16). Multiline tokens require LineIndex, which must use the same code units.
+type SemanticTokensBuilder struct {
+	Legend SemanticTokensLegend
+
+	// If false, multiline tokens are split into a token per line
+	MultilineTokenSupport bool
+
+	// If false, overlapping tokens are an error
+	OverlappingTokenSupport bool
+
+	// Required for encoding multiline tokens
+	LineIndex *LineIndex
+
+	tokens []SemanticToken
+}
+
+// The capabilities can be nil.
+func NewSemanticTokensBuilder(legend SemanticTokensLegend, capabilities *SemanticTokensClientCapabilities) *SemanticTokensBuilder {
+	self := SemanticTokensBuilder{Legend: legend}
+	if capabilities != nil {
+		self.MultilineTokenSupport = isTrue(capabilities.MultilineTokenSupport)
+		self.OverlappingTokenSupport = isTrue(capabilities.OverlappingTokenSupport)
+	}
+	return &self
+}
+
+func (self *SemanticTokensBuilder) Add(range_ Range, type_ SemanticTokenType, modifiers ...SemanticTokenModifier) {
+	self.tokens = append(self.tokens, SemanticToken{Range: range_, Type: type_, Modifiers: modifiers})
+}
+
+func (self *SemanticTokensBuilder) AddToken(token SemanticToken) {
+	self.tokens = append(self.tokens, token)
+}
+
+func (self *SemanticTokensBuilder) Tokens() []SemanticToken {
+	return self.tokens
+}
+
+func (self *SemanticTokensBuilder) Reset() {
+	self.tokens = nil
+}
+
+// Modifier sets are bit flags in a uinteger, which has 31 bits.
+const maxSemanticTokenModifiers = 31
+
+// Sorts and validates the tokens and encodes them into the relative format.
+func (self *SemanticTokensBuilder) Encode() ([]UInteger, error) {
+	if len(self.Legend.TokenModifiers) > maxSemanticTokenModifiers {
+		return nil, fmt.Errorf("semantic tokens legend has more than %d modifiers: %d", maxSemanticTokenModifiers, len(self.Legend.TokenModifiers))
+	}
+
+	types := make(map[SemanticTokenType]UInteger)
+	for index, type_ := range self.Legend.TokenTypes {
+		types[SemanticTokenType(type_)] = UInteger(index)
+	}
+
+	modifiers := make(map[SemanticTokenModifier]UInteger)
+	for index, modifier := range self.Legend.TokenModifiers {
+		modifiers[SemanticTokenModifier(modifier)] = 1 << index
+	}
+
+	tokens := make([]SemanticToken, len(self.tokens))
+	copy(tokens, self.tokens)
+	sort.SliceStable(tokens, func(i int, j int) bool {
+		return tokens[i].Range.Start.lessThan(tokens[j].Range.Start)
+	})
+
+	pieces := make([]encodedSemanticTokenPiece, 0, len(tokens))
+	var maxEnd *Position
+
+	for _, token := range tokens {
+		if token.Range.End.lessThan(token.Range.Start) {
+			return nil, fmt.Errorf("semantic token range ends before it starts: %s", token)
+		}
+
+		type_, ok := types[token.Type]
+		if !ok {
+			return nil, fmt.Errorf("semantic token type not in legend: %s", token)
+		}
+
+		var modifierSet UInteger
+		for _, modifier := range token.Modifiers {
+			if bit, ok := modifiers[modifier]; ok {
+				modifierSet |= bit
+			} else {
+				return nil, fmt.Errorf("semantic token modifier not in legend: %s", token)
+			}
+		}
+
+		// Compare with the furthest end so far, because a token can overlap a token
+		// before the previous one
+		if !self.OverlappingTokenSupport && (maxEnd != nil) && token.Range.Start.lessThan(*maxEnd) {
+			return nil, fmt.Errorf("semantic token overlaps a previous token: %s", token)
+		}
+		if (maxEnd == nil) || maxEnd.lessThan(token.Range.End) {
+			end := token.Range.End
+			maxEnd = &end
+		}
+
+		if tokenPieces, err := self.split(token.Range); err == nil {
+			for _, piece := range tokenPieces {
+				pieces = append(pieces, encodedSemanticTokenPiece{piece, type_, modifierSet})
+			}
+		} else {
+			return nil, fmt.Errorf("%s: %s", err.Error(), token)
+		}
+	}
+
+	// When overlapping, the pieces of a split multiline token can come after the start
+	// of the following tokens
+	if self.OverlappingTokenSupport {
+		sort.SliceStable(pieces, func(i int, j int) bool {
+			return pieces[i].start.lessThan(pieces[j].start)
+		})
+	}
+
+	data := make([]UInteger, 0, len(pieces)*5)
+	var previous Position
+	for _, piece := range pieces {
+		line := piece.start.Line - previous.Line
+		character := piece.start.Character
+		if line == 0 {
+			character -= previous.Character
+		}
+		data = append(data, line, character, piece.length, piece.type_, piece.modifierSet)
+		previous = piece.start
+	}
+
+	return data, nil
+}
+
+func (self *SemanticTokensBuilder) Build(resultID *string) (*SemanticTokens, error) {
+	if data, err := self.Encode(); err == nil {
+		return &SemanticTokens{ResultID: resultID, Data: data}, nil
+	} else {
+		return nil, err
+	}
+}
+
+type semanticTokenPiece struct {
+	start  Position
+	length UInteger
+}
+
+type encodedSemanticTokenPiece struct {
+	semanticTokenPiece
+	type_       UInteger
+	modifierSet UInteger
+}
+
+func (self *SemanticTokensBuilder) split(range_ Range) ([]semanticTokenPiece, error) {
+	if range_.Start.Line == range_.End.Line {
+		return []semanticTokenPiece{{range_.Start, range_.End.Character - range_.Start.Character}}, nil
+	}
+
+	if self.LineIndex == nil {
+		return nil, fmt.Errorf("multiline semantic token requires a line index")
+	}
+
+	if self.MultilineTokenSupport {
+		// The length includes the line breaks
+		start, end := self.LineIndex.IndexesOf(range_)
+		return []semanticTokenPiece{{range_.Start, UInteger(self.LineIndex.countCodeUnits(start, end))}}, nil
+	}
+
+	var pieces []semanticTokenPiece
+	for line := range_.Start.Line; line <= range_.End.Line; line++ {
+		var start UInteger
+		if line == range_.Start.Line {
+			start = range_.Start.Character
+		}
+
+		var end UInteger
+		if line == range_.End.Line {
+			end = range_.End.Character
+		} else {
+			end = self.LineIndex.lineLength(line)
+		}
+
+		if end > start {
+			pieces = append(pieces, semanticTokenPiece{Position{Line: line, Character: start}, end - start})
+		}
+	}
+	return pieces, nil
+}
+
+// Decodes relative-format data into absolute tokens. Intended mainly for tests and debugging.
+//
+// Tokens are always decoded as single-line, so the end character of a multiline token
+// (encoded with MultilineTokenSupport) will be beyond the end of its line.
+func DecodeSemanticTokens(data []UInteger, legend SemanticTokensLegend) ([]SemanticToken, error) {
+	if len(data)%5 != 0 {
+		return nil, fmt.Errorf("semantic tokens data length is not a multiple of 5: %d", len(data))
+	}
+
+	tokens := make([]SemanticToken, 0, len(data)/5)
+	var previous Position
+
+	for index := 0; index < len(data); index += 5 {
+		start := Position{Line: previous.Line + data[index], Character: data[index+1]}
+		if data[index] == 0 {
+			start.Character += previous.Character
+		}
+		previous = start
+
+		type_ := data[index+3]
+		if int(type_) >= len(legend.TokenTypes) {
+			return nil, fmt.Errorf("semantic token type index not in legend: %d", type_)
+		}
+
+		token := SemanticToken{
+			Range: Range{Start: start, End: Position{Line: start.Line, Character: start.Character + data[index+2]}},
+			Type:  SemanticTokenType(legend.TokenTypes[type_]),
+		}
+
+		modifierSet := data[index+4]
+		for bit := 0; modifierSet != 0; bit++ {
+			if modifierSet&1 != 0 {
+				if bit >= len(legend.TokenModifiers) {
+					return nil, fmt.Errorf("semantic token modifier index not in legend: %d", bit)
+				}
+				token.Modifiers = append(token.Modifiers, SemanticTokenModifier(legend.TokenModifiers[bit]))
+			}
+			modifierSet >>= 1
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// Utils
+
+func (self Position) lessThan(position Position) bool {
+	return (self.Line < position.Line) || ((self.Line == position.Line) && (self.Character < position.Character))
+}
+
+// Without the line break.
+func (self *LineIndex) lineLength(line UInteger) UInteger {
+	start, end := self.lineBounds(int(line))
+	if (end > start) && (self.content[end-1] == '\r') {
+		end--
+	}
+	return UInteger(self.countCodeUnits(start, end))
+}
+
+func (self *LineIndex) countCodeUnits(start int, end int) int {
+	var count int
+	for offset := start; offset < end; {
+		r, width := utf8.DecodeRuneInString(self.content[offset:])
+		count += self.codeUnits(r, width)
+		offset += width
+	}
+	return count
+}
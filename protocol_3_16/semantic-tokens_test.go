@@ -0,0 +1,189 @@
+package protocol
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func semanticTokenRange(line UInteger, start UInteger, end UInteger) Range {
+	return Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}
+}
+
+func TestSemanticTokensEncode(t *testing.T) {
+	// The example from the specification
+	legend := SemanticTokensLegend{
+		TokenTypes:     []string{"property", "type", "class"},
+		TokenModifiers: []string{"private", "static"},
+	}
+
+	builder := NewSemanticTokensBuilder(legend, nil)
+	builder.Add(semanticTokenRange(5, 2, 9), "class")
+	builder.Add(semanticTokenRange(2, 5, 8), "property", "private", "static")
+	builder.Add(semanticTokenRange(2, 10, 14), "type")
+
+	data, err := builder.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []UInteger{2, 5, 3, 0, 3, 0, 5, 4, 1, 0, 3, 2, 7, 2, 0}; !reflect.DeepEqual(data, expected) {
+		t.Errorf("got %v, expected %v", data, expected)
+	}
+}
+
+func TestSemanticTokensRoundTrip(t *testing.T) {
+	legend := NewDefaultSemanticTokensLegend()
+	random := rand.New(rand.NewSource(1))
+
+	for iteration := 0; iteration < 100; iteration++ {
+		builder := NewSemanticTokensBuilder(legend, nil)
+		var expected []SemanticToken
+
+		// Non-overlapping tokens, added in random order
+		for line := UInteger(0); line < 20; line++ {
+			var character UInteger
+			for random.Intn(4) != 0 {
+				character += UInteger(random.Intn(5))
+				length := UInteger(random.Intn(10) + 1)
+				token := SemanticToken{
+					Range: semanticTokenRange(line, character, character+length),
+					Type:  SemanticTokenTypes[random.Intn(len(SemanticTokenTypes))],
+				}
+				for _, modifier := range SemanticTokenModifiers {
+					if random.Intn(4) == 0 {
+						token.Modifiers = append(token.Modifiers, modifier)
+					}
+				}
+				expected = append(expected, token)
+				character += length
+			}
+		}
+
+		for _, index := range random.Perm(len(expected)) {
+			builder.AddToken(expected[index])
+		}
+
+		data, err := builder.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens, err := DecodeSemanticTokens(data, legend)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(tokens) != len(expected) {
+			t.Fatalf("decoded %d tokens, expected %d", len(tokens), len(expected))
+		}
+		for index, token := range tokens {
+			if token.String() != expected[index].String() {
+				t.Errorf("token %d: got %s, expected %s", index, token, expected[index])
+			}
+		}
+	}
+}
+
+func TestSemanticTokensOverlap(t *testing.T) {
+	legend := NewDefaultSemanticTokensLegend()
+
+	tests := []struct {
+		ranges   []Range
+		overlaps bool
+	}{
+		{[]Range{semanticTokenRange(0, 0, 5), semanticTokenRange(0, 5, 10)}, false},
+		{[]Range{semanticTokenRange(0, 0, 5), semanticTokenRange(1, 0, 5)}, false},
+		{[]Range{semanticTokenRange(0, 0, 5), semanticTokenRange(0, 4, 10)}, true},
+		{[]Range{semanticTokenRange(0, 0, 10), semanticTokenRange(0, 2, 4)}, true},
+		{[]Range{semanticTokenRange(0, 0, 10), semanticTokenRange(0, 2, 4), semanticTokenRange(0, 6, 8)}, true},
+		{[]Range{{Start: Position{Line: 0, Character: 5}, End: Position{Line: 2, Character: 0}}, semanticTokenRange(1, 0, 3)}, true},
+	}
+
+	for _, test := range tests {
+		for _, overlapping := range []bool{false, true} {
+			builder := NewSemanticTokensBuilder(legend, &SemanticTokensClientCapabilities{OverlappingTokenSupport: &overlapping})
+			builder.LineIndex = NewLineIndex("0123456789\n0123456789\n0123456789\n")
+			for _, range_ := range test.ranges {
+				builder.Add(range_, SemanticTokenTypeVariable)
+			}
+
+			_, err := builder.Encode()
+			if expected := test.overlaps && !overlapping; (err != nil) != expected {
+				t.Errorf("%v (overlapping support %t): got error %v", test.ranges, overlapping, err)
+			}
+		}
+	}
+}
+
+func TestSemanticTokensOverlappingMultiline(t *testing.T) {
+	// The pieces of the multiline token must be interleaved with the token it contains
+	legend := NewDefaultSemanticTokensLegend()
+	overlapping := true
+	builder := NewSemanticTokensBuilder(legend, &SemanticTokensClientCapabilities{OverlappingTokenSupport: &overlapping})
+	builder.LineIndex = NewLineIndex("/* comment\n   @param x\n*/\n")
+	builder.Add(Range{Start: Position{Line: 0, Character: 0}, End: Position{Line: 2, Character: 2}}, SemanticTokenTypeComment)
+	builder.Add(semanticTokenRange(1, 3, 9), SemanticTokenTypeKeyword)
+
+	data, err := builder.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := DecodeSemanticTokens(data, legend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, token := range tokens {
+		got = append(got, token.String())
+	}
+	expected := []string{
+		fmt.Sprintf("%s comment", rangeString(semanticTokenRange(0, 0, 10))),
+		fmt.Sprintf("%s comment", rangeString(semanticTokenRange(1, 0, 11))),
+		fmt.Sprintf("%s keyword", rangeString(semanticTokenRange(1, 3, 9))),
+		fmt.Sprintf("%s comment", rangeString(semanticTokenRange(2, 0, 2))),
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestSemanticTokensModifiers(t *testing.T) {
+	modifiers := make([]string, 32)
+	for index := range modifiers {
+		modifiers[index] = fmt.Sprintf("modifier%d", index)
+	}
+
+	// The last bit of a uinteger
+	legend := SemanticTokensLegend{TokenTypes: []string{"type"}, TokenModifiers: modifiers[:31]}
+	builder := NewSemanticTokensBuilder(legend, nil)
+	builder.Add(semanticTokenRange(0, 0, 1), "type", "modifier0", "modifier30")
+
+	data, err := builder.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := UInteger(1 | 1<<30); data[4] != expected {
+		t.Errorf("got modifier set %b, expected %b", data[4], expected)
+	}
+	tokens, err := DecodeSemanticTokens(data, legend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(tokens[0].Modifiers))
+	for index, modifier := range tokens[0].Modifiers {
+		got[index] = string(modifier)
+	}
+	sort.Strings(got)
+	if expected := []string{"modifier0", "modifier30"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+
+	// Too many modifiers
+	builder.Legend.TokenModifiers = modifiers
+	if _, err := builder.Encode(); err == nil {
+		t.Errorf("expected an error for %d modifiers", len(modifiers))
+	}
+}
@@ -0,0 +1,472 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tliron/glsp/uri"
+)
+
+//
+// EditableWorkspace
+//
+
+// A set of documents to which a [WorkspaceEdit] can be applied.
+//
+// See [MemoryWorkspace] and [FileWorkspace].
+type EditableWorkspace interface {
+	Stat(uri DocumentUri) (exists bool, isDirectory bool, err error)
+	Read(uri DocumentUri) (string, error)
+	Write(uri DocumentUri, content string) error // creates the file (and its parent directories) if necessary
+	Rename(oldUri DocumentUri, newUri DocumentUri) error
+	Delete(uri DocumentUri, recursive bool) error
+
+	// Return false if the version is unknown, e.g. because the document is not open
+	Version(uri DocumentUri) (Integer, bool)
+}
+
+//
+// WorkspaceEditError
+//
+
+type WorkspaceEditError struct {
+	// Index in DocumentChanges (or nil if the error is in Changes)
+	FailedChange *UInteger
+
+	Err error
+}
+
+// ([error] interface)
+func (self *WorkspaceEditError) Error() string {
+	if self.FailedChange != nil {
+		return fmt.Sprintf("document change %d: %s", *self.FailedChange, self.Err.Error())
+	} else {
+		return self.Err.Error()
+	}
+}
+
+// (for [errors.Unwrap])
+func (self *WorkspaceEditError) Unwrap() error {
+	return self.Err
+}
+
+// Applies DocumentChanges in order if there are any, otherwise Changes. (Like clients, which
+// prefer DocumentChanges if they support them, we never apply both.) Positions are in UTF-16
+// code units.
+//
+// Uses the "abort" failure handling strategy: we stop at the first failure and return a
+// [*WorkspaceEditError], but the changes before it stay applied. Text edits for a single
+// document are validated before they are written, so a document is never partially edited.
+func ApplyWorkspaceEdit(workspace EditableWorkspace, edit *WorkspaceEdit) error {
+	return ApplyWorkspaceEditWithCodeUnits(workspace, edit, UTF16CodeUnits)
+}
+
+func ApplyWorkspaceEditWithCodeUnits(workspace EditableWorkspace, edit *WorkspaceEdit, codeUnits CodeUnitsFunc) error {
+	if len(edit.DocumentChanges) > 0 {
+		for index, change := range edit.DocumentChanges {
+			if err := applyDocumentChange(workspace, change, codeUnits); err != nil {
+				index_ := UInteger(index)
+				return &WorkspaceEditError{FailedChange: &index_, Err: err}
+			}
+		}
+
+		return nil
+	}
+
+	// Sorted for deterministic results
+	uris := make([]DocumentUri, 0, len(edit.Changes))
+	for uri := range edit.Changes {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		if err := applyTextEditsToWorkspace(workspace, uri, edit.Changes[uri], codeUnits); err != nil {
+			return &WorkspaceEditError{Err: err}
+		}
+	}
+
+	return nil
+}
+
+func applyDocumentChange(workspace EditableWorkspace, change any, codeUnits CodeUnitsFunc) error {
+	switch change_ := change.(type) {
+	case TextDocumentEdit:
+		return applyTextDocumentEdit(workspace, &change_, codeUnits)
+	case *TextDocumentEdit:
+		return applyTextDocumentEdit(workspace, change_, codeUnits)
+	case CreateFile:
+		return applyCreateFile(workspace, &change_)
+	case *CreateFile:
+		return applyCreateFile(workspace, change_)
+	case RenameFile:
+		return applyRenameFile(workspace, &change_)
+	case *RenameFile:
+		return applyRenameFile(workspace, change_)
+	case DeleteFile:
+		return applyDeleteFile(workspace, &change_)
+	case *DeleteFile:
+		return applyDeleteFile(workspace, change_)
+	default:
+		return fmt.Errorf("unsupported document change: %T", change)
+	}
+}
+
+func applyTextDocumentEdit(workspace EditableWorkspace, edit *TextDocumentEdit, codeUnits CodeUnitsFunc) error {
+	uri := edit.TextDocument.URI
+
+	if edit.TextDocument.Version != nil {
+		if version, ok := workspace.Version(uri); ok && (version != *edit.TextDocument.Version) {
+			return fmt.Errorf("version mismatch for %s: edit is for %d but document is at %d", uri, *edit.TextDocument.Version, version)
+		}
+	}
+
+	if edits, err := ToTextEdits(edit.Edits); err == nil {
+		return applyTextEditsToWorkspace(workspace, uri, edits, codeUnits)
+	} else {
+		return err
+	}
+}
+
+func applyTextEditsToWorkspace(workspace EditableWorkspace, uri DocumentUri, edits []TextEdit, codeUnits CodeUnitsFunc) error {
+	content, err := workspace.Read(uri)
+	if err != nil {
+		return err
+	}
+
+	if content, err = ApplyTextEditsWithCodeUnits(content, edits, codeUnits); err == nil {
+		return workspace.Write(uri, content)
+	} else {
+		return fmt.Errorf("%s: %w", uri, err)
+	}
+}
+
+func applyCreateFile(workspace EditableWorkspace, create *CreateFile) error {
+	exists, _, err := workspace.Stat(create.URI)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		// Overwrite wins over IgnoreIfExists
+		if (create.Options != nil) && isTrue(create.Options.Overwrite) {
+			return workspace.Write(create.URI, "")
+		} else if (create.Options != nil) && isTrue(create.Options.IgnoreIfExists) {
+			return nil
+		} else {
+			return fmt.Errorf("cannot create, already exists: %s", create.URI)
+		}
+	}
+
+	return workspace.Write(create.URI, "")
+}
+
+func applyRenameFile(workspace EditableWorkspace, rename *RenameFile) error {
+	if exists, _, err := workspace.Stat(rename.OldURI); err == nil {
+		if !exists {
+			return fmt.Errorf("cannot rename, does not exist: %s", rename.OldURI)
+		}
+	} else {
+		return err
+	}
+
+	exists, _, err := workspace.Stat(rename.NewURI)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		// Overwrite wins over IgnoreIfExists
+		if (rename.Options != nil) && isTrue(rename.Options.Overwrite) {
+			if err := workspace.Delete(rename.NewURI, true); err != nil {
+				return err
+			}
+		} else if (rename.Options != nil) && isTrue(rename.Options.IgnoreIfExists) {
+			return nil
+		} else {
+			return fmt.Errorf("cannot rename, target already exists: %s", rename.NewURI)
+		}
+	}
+
+	return workspace.Rename(rename.OldURI, rename.NewURI)
+}
+
+func applyDeleteFile(workspace EditableWorkspace, delete *DeleteFile) error {
+	exists, _, err := workspace.Stat(delete.URI)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		if (delete.Options != nil) && isTrue(delete.Options.IgnoreIfNotExists) {
+			return nil
+		} else {
+			return fmt.Errorf("cannot delete, does not exist: %s", delete.URI)
+		}
+	}
+
+	return workspace.Delete(delete.URI, (delete.Options != nil) && isTrue(delete.Options.Recursive))
+}
+
+func isTrue(value *bool) bool {
+	return (value != nil) && *value
+}
+
+//
+// MemoryWorkspace
+//
+
+// An in-memory [EditableWorkspace]. Directories are implied by the URIs of the files.
+type MemoryWorkspace struct {
+	Files    map[DocumentUri]string
+	Versions map[DocumentUri]Integer
+
+	lock sync.Mutex
+}
+
+func NewMemoryWorkspace() *MemoryWorkspace {
+	return &MemoryWorkspace{
+		Files:    make(map[DocumentUri]string),
+		Versions: make(map[DocumentUri]Integer),
+	}
+}
+
+// Includes the content and versions of all documents.
+func NewMemoryWorkspaceFromDocuments(documents *Documents) *MemoryWorkspace {
+	self := NewMemoryWorkspace()
+	for _, document := range documents.List() {
+		self.Files[document.URI] = document.Content
+		self.Versions[document.URI] = document.Version
+	}
+	return self
+}
+
+// ([EditableWorkspace] interface)
+func (self *MemoryWorkspace) Stat(uri DocumentUri) (bool, bool, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if _, ok := self.Files[uri]; ok {
+		return true, false, nil
+	}
+
+	prefix := directoryPrefix(uri)
+	for uri_ := range self.Files {
+		if strings.HasPrefix(uri_, prefix) {
+			return true, true, nil
+		}
+	}
+
+	return false, false, nil
+}
+
+// ([EditableWorkspace] interface)
+func (self *MemoryWorkspace) Read(uri DocumentUri) (string, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if content, ok := self.Files[uri]; ok {
+		return content, nil
+	} else {
+		return "", fmt.Errorf("file not found: %s", uri)
+	}
+}
+
+// ([EditableWorkspace] interface)
+func (self *MemoryWorkspace) Write(uri DocumentUri, content string) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if version, ok := self.Versions[uri]; ok {
+		self.Versions[uri] = version + 1
+	}
+	self.Files[uri] = content
+	return nil
+}
+
+// ([EditableWorkspace] interface)
+func (self *MemoryWorkspace) Rename(oldUri DocumentUri, newUri DocumentUri) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if content, ok := self.Files[oldUri]; ok {
+		delete(self.Files, oldUri)
+		delete(self.Versions, oldUri)
+		self.Files[newUri] = content
+		return nil
+	}
+
+	// Directory
+	oldPrefix := directoryPrefix(oldUri)
+	newPrefix := directoryPrefix(newUri)
+	found := false
+	for uri, content := range self.Files {
+		if strings.HasPrefix(uri, oldPrefix) {
+			delete(self.Files, uri)
+			delete(self.Versions, uri)
+			self.Files[newPrefix+uri[len(oldPrefix):]] = content
+			found = true
+		}
+	}
+
+	if found {
+		return nil
+	} else {
+		return fmt.Errorf("file not found: %s", oldUri)
+	}
+}
+
+// ([EditableWorkspace] interface)
+func (self *MemoryWorkspace) Delete(uri DocumentUri, recursive bool) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if _, ok := self.Files[uri]; ok {
+		delete(self.Files, uri)
+		delete(self.Versions, uri)
+		return nil
+	}
+
+	// Directory
+	prefix := directoryPrefix(uri)
+	var uris []DocumentUri
+	for uri_ := range self.Files {
+		if strings.HasPrefix(uri_, prefix) {
+			uris = append(uris, uri_)
+		}
+	}
+
+	if len(uris) == 0 {
+		return fmt.Errorf("file not found: %s", uri)
+	} else if !recursive {
+		return fmt.Errorf("cannot delete non-empty directory without recursive: %s", uri)
+	}
+
+	for _, uri_ := range uris {
+		delete(self.Files, uri_)
+		delete(self.Versions, uri_)
+	}
+
+	return nil
+}
+
+// ([EditableWorkspace] interface)
+func (self *MemoryWorkspace) Version(uri DocumentUri) (Integer, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	version, ok := self.Versions[uri]
+	return version, ok
+}
+
+func directoryPrefix(uri DocumentUri) string {
+	return strings.TrimSuffix(uri, "/") + "/"
+}
+
+//
+// FileWorkspace
+//
+
+// An [EditableWorkspace] for the local filesystem. Supports "file:" URIs only.
+// Versions are always unknown.
+type FileWorkspace struct {
+	FileMode      fs.FileMode // for new files
+	DirectoryMode fs.FileMode // for new directories
+}
+
+func NewFileWorkspace() *FileWorkspace {
+	return &FileWorkspace{
+		FileMode:      0644,
+		DirectoryMode: 0755,
+	}
+}
+
+// ([EditableWorkspace] interface)
+func (self *FileWorkspace) Stat(uri DocumentUri) (bool, bool, error) {
+	path, err := self.toPath(uri)
+	if err != nil {
+		return false, false, err
+	}
+
+	if stat, err := os.Stat(path); err == nil {
+		return true, stat.IsDir(), nil
+	} else if errors.Is(err, fs.ErrNotExist) {
+		return false, false, nil
+	} else {
+		return false, false, err
+	}
+}
+
+// ([EditableWorkspace] interface)
+func (self *FileWorkspace) Read(uri DocumentUri) (string, error) {
+	if path, err := self.toPath(uri); err == nil {
+		if content, err := os.ReadFile(path); err == nil {
+			return string(content), nil
+		} else {
+			return "", err
+		}
+	} else {
+		return "", err
+	}
+}
+
+// ([EditableWorkspace] interface)
+func (self *FileWorkspace) Write(uri DocumentUri, content string) error {
+	path, err := self.toPath(uri)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), self.DirectoryMode); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(content), self.FileMode)
+}
+
+// ([EditableWorkspace] interface)
+func (self *FileWorkspace) Rename(oldUri DocumentUri, newUri DocumentUri) error {
+	oldPath, err := self.toPath(oldUri)
+	if err != nil {
+		return err
+	}
+
+	newPath, err := self.toPath(newUri)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newPath), self.DirectoryMode); err != nil {
+		return err
+	}
+
+	return os.Rename(oldPath, newPath)
+}
+
+// ([EditableWorkspace] interface)
+func (self *FileWorkspace) Delete(uri DocumentUri, recursive bool) error {
+	if path, err := self.toPath(uri); err == nil {
+		if recursive {
+			return os.RemoveAll(path)
+		} else {
+			return os.Remove(path)
+		}
+	} else {
+		return err
+	}
+}
+
+// ([EditableWorkspace] interface)
+func (self *FileWorkspace) Version(uri DocumentUri) (Integer, bool) {
+	return 0, false
+}
+
+func (self *FileWorkspace) toPath(uri_ DocumentUri) (string, error) {
+	return uri.URIToPath(uri_, uri.NativePathStyle)
+}
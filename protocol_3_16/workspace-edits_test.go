@@ -0,0 +1,73 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestWorkspaceEditJSON(t *testing.T) {
+	version := Integer(3)
+	overwrite := true
+	edit := WorkspaceEdit{
+		DocumentChanges: []any{
+			CreateFile{Kind: string(ResourceOperationKindCreate), URI: "file:///a.txt"},
+			TextDocumentEdit{
+				TextDocument: OptionalVersionedTextDocumentIdentifier{
+					TextDocumentIdentifier: TextDocumentIdentifier{URI: "file:///a.txt"},
+					Version:                &version,
+				},
+				Edits: []any{
+					TextEdit{NewText: "hello"},
+					AnnotatedTextEdit{TextEdit: TextEdit{NewText: "!"}, AnnotationID: "annotation"},
+				},
+			},
+			RenameFile{Kind: string(ResourceOperationKindRename), OldURI: "file:///a.txt", NewURI: "file:///b.txt", Options: &RenameFileOptions{Overwrite: &overwrite}},
+			DeleteFile{Kind: string(ResourceOperationKindDelete), URI: "file:///b.txt"},
+		},
+	}
+
+	data, err := json.Marshal(&edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var edit_ WorkspaceEdit
+	if err := json.Unmarshal(data, &edit_); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(edit, edit_) {
+		t.Errorf("round trip changed the edit:\n%#v\n%#v", edit, edit_)
+	}
+}
+
+func TestApplyWorkspaceEditPrefersDocumentChanges(t *testing.T) {
+	workspace := NewMemoryWorkspace()
+	if err := workspace.Write("file:///a.txt", "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	insert := Range{}
+	edit := WorkspaceEdit{
+		Changes: map[DocumentUri][]TextEdit{
+			"file:///a.txt": {{Range: insert, NewText: "XX"}},
+		},
+		DocumentChanges: []any{
+			TextDocumentEdit{
+				TextDocument: OptionalVersionedTextDocumentIdentifier{TextDocumentIdentifier: TextDocumentIdentifier{URI: "file:///a.txt"}},
+				Edits:        []any{TextEdit{Range: insert, NewText: "XX"}},
+			},
+		},
+	}
+
+	if err := ApplyWorkspaceEdit(workspace, &edit); err != nil {
+		t.Fatal(err)
+	}
+
+	if content, err := workspace.Read("file:///a.txt"); err != nil {
+		t.Fatal(err)
+	} else if content != "XXhello" {
+		t.Errorf("got %q, expected %q", content, "XXhello")
+	}
+}
@@ -0,0 +1,83 @@
+package protocol
+
+import (
+	protocol316 "github.com/tliron/glsp/protocol_3_16"
+)
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#positionEncodingKind
+
+/**
+ * A type indicating how positions are encoded,
+ * specifically what column offsets mean.
+ *
+ * @since 3.17.0
+ */
+type PositionEncodingKind string
+
+const (
+	/**
+	 * Character offsets count UTF-8 code units (e.g bytes).
+	 */
+	PositionEncodingKindUTF8 = PositionEncodingKind("utf-8")
+
+	/**
+	 * Character offsets count UTF-16 code units.
+	 *
+	 * This is the default and must always be supported
+	 * by servers
+	 */
+	PositionEncodingKindUTF16 = PositionEncodingKind("utf-16")
+
+	/**
+	 * Character offsets count UTF-32 code units.
+	 *
+	 * Implementation note: these are the same as Unicode code points,
+	 * so this `PositionEncodingKind` may also be used for an
+	 * encoding-agnostic representation of character offsets.
+	 */
+	PositionEncodingKindUTF32 = PositionEncodingKind("utf-32")
+)
+
+// Unknown encodings are treated as UTF-16.
+func (self PositionEncodingKind) CodeUnits() protocol316.CodeUnitsFunc {
+	switch self {
+	case PositionEncodingKindUTF8:
+		return protocol316.UTF8CodeUnits
+	case PositionEncodingKindUTF32:
+		return protocol316.UTF32CodeUnits
+	default:
+		return protocol316.UTF16CodeUnits
+	}
+}
+
+func (self PositionEncodingKind) NewLineIndex(content string) *protocol316.LineIndex {
+	return protocol316.NewLineIndexWithCodeUnits(content, self.CodeUnits())
+}
+
+// Go strings are UTF-8, so it is the cheapest for us
+var DefaultPositionEncodings = []PositionEncodingKind{PositionEncodingKindUTF8, PositionEncodingKindUTF32, PositionEncodingKindUTF16}
+
+// Chooses the first of the preferred encodings that is supported by the client. If preferred
+// is empty will use [DefaultPositionEncodings]. Falls back to UTF-16, which must always be
+// supported.
+//
+// Set the result in [ServerCapabilities.PositionEncoding].
+func ChoosePositionEncoding(capabilities *ClientCapabilities, preferred ...PositionEncodingKind) PositionEncodingKind {
+	if (capabilities == nil) || (capabilities.General == nil) || (len(capabilities.General.PositionEncodings) == 0) {
+		return PositionEncodingKindUTF16
+	}
+
+	if len(preferred) == 0 {
+		preferred = DefaultPositionEncodings
+	}
+
+	for _, encoding := range preferred {
+		for _, encoding_ := range capabilities.General.PositionEncodings {
+			if encoding == encoding_ {
+				return encoding
+			}
+		}
+	}
+
+	return PositionEncodingKindUTF16
+}
@@ -0,0 +1,73 @@
+package protocol
+
+import (
+	"github.com/tliron/glsp"
+	protocol316 "github.com/tliron/glsp/protocol_3_16"
+)
+
+type initializeParamsKey struct{}
+
+// Keeps the params for the lifetime of the connection. This is called automatically by
+// [Handler] for "initialize".
+// The embedded 3.16 params are also kept for [protocol316.GetInitializeParams].
+func SetInitializeParams(context *glsp.Context, params *InitializeParams) {
+	if context.State != nil {
+		context.State.Set(initializeParamsKey{}, params)
+		protocol316.SetInitializeParams(context, &params.InitializeParams)
+	}
+}
+
+// Returns the params of the connection's "initialize" request, or nil if it has not been
+// received yet.
+func GetInitializeParams(context *glsp.Context) *InitializeParams {
+	if context.State != nil {
+		if params, ok := context.State.Get(initializeParamsKey{}); ok {
+			return params.(*InitializeParams)
+		}
+	}
+	return nil
+}
+
+// Returns the client capabilities of the connection, which are empty if "initialize" has
+// not been received yet. (Unlike [protocol316.GetClientCapabilities] it never returns nil,
+// because the promoted 3.16 Supports methods cannot be called on a nil 3.17 pointer.) Use
+// [GetInitializeParams] to check whether "initialize" has been received.
+func GetClientCapabilities(context *glsp.Context) *ClientCapabilities {
+	if params := GetInitializeParams(context); params != nil {
+		return &params.Capabilities
+	}
+	return new(ClientCapabilities)
+}
+
+//
+// ClientCapabilities
+//
+
+func (self *ClientCapabilities) SupportsPositionEncoding(encoding PositionEncodingKind) bool {
+	if (self != nil) && (self.General != nil) && (self.General.PositionEncodings != nil) {
+		for _, encoding_ := range self.General.PositionEncodings {
+			if encoding == encoding_ {
+				return true
+			}
+		}
+	}
+	// UTF-16 is mandatory
+	return encoding == PositionEncodingKindUTF16
+}
+
+func (self *ClientCapabilities) SupportsDiagnosticPull() bool {
+	return (self != nil) && (self.TextDocument != nil) && (self.TextDocument.Diagnostic != nil)
+}
+
+func (self *ClientCapabilities) SupportsDiagnosticRefresh() bool {
+	if (self != nil) && (self.Workspace != nil) && (self.Workspace.Diagnostics != nil) {
+		return isTrue(self.Workspace.Diagnostics.RefreshSupport)
+	}
+	return false
+}
+
+// Utils
+
+func isTrue(value *bool) bool {
+	return (value != nil) && *value
+}
@@ -0,0 +1,30 @@
+package protocol
+
+import (
+	contextpkg "context"
+
+	"github.com/tliron/glsp"
+	protocol316 "github.com/tliron/glsp/protocol_3_16"
+)
+
+//
+// Client
+//
+
+// Typed access to the requests and notifications that the server can send to the client,
+// including those added in 3.17
+type Client struct {
+	*protocol316.Client
+
+	context *glsp.Context
+}
+
+func NewClient(context *glsp.Context) *Client {
+	return &Client{protocol316.NewClient(context), context}
+}
+
+// Workspace
+
+func (self *Client) DiagnosticRefresh(context contextpkg.Context) error {
+	return self.context.CallWithContext(context, ServerWorkspaceDiagnosticRefresh, nil, nil)
+}
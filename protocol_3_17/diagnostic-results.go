@@ -0,0 +1,173 @@
+package protocol
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	protocol316 "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Returns the diagnostics for the document and optionally for related documents
+// (which can be nil).
+type ComputeDiagnosticsFunc func() (diagnostics []protocol316.Diagnostic, related map[protocol316.DocumentUri][]protocol316.Diagnostic, err error)
+
+//
+// DiagnosticResults
+//
+
+// Issues result IDs for "textDocument/diagnostic" and returns "unchanged" reports when
+// the diagnostics have not changed.
+//
+// Result IDs are hashes of the diagnostics. If you also provide document versions then
+// diagnostics are not recomputed for a version that was already computed.
+type DiagnosticResults struct {
+	results map[diagnosticResultKey]*diagnosticResult
+	lock    sync.Mutex
+}
+
+type diagnosticResultKey struct {
+	identifier string
+	uri        protocol316.DocumentUri
+}
+
+type diagnosticResult struct {
+	resultID    string
+	version     *protocol316.Integer
+	diagnostics []protocol316.Diagnostic
+	related     map[protocol316.DocumentUri][]protocol316.Diagnostic
+}
+
+func NewDiagnosticResults() *DiagnosticResults {
+	return &DiagnosticResults{
+		results: make(map[diagnosticResultKey]*diagnosticResult),
+	}
+}
+
+// Returns a [RelatedFullDocumentDiagnosticReport] or a [RelatedUnchangedDocumentDiagnosticReport].
+//
+// Version can be nil, in which case compute is always called.
+//
+// The request does not carry the client's result IDs for related documents, so they
+// are reported as "unchanged" only if their result IDs are in previousResultIds, e.g.
+// as the client provided them in [WorkspaceDiagnosticParams]. Otherwise they are
+// always reported in full.
+func (self *DiagnosticResults) Report(params *DocumentDiagnosticParams, version *protocol316.Integer, compute ComputeDiagnosticsFunc, previousResultIds ...PreviousResultId) (DocumentDiagnosticReport, error) {
+	var identifier string
+	if params.Identifier != nil {
+		identifier = *params.Identifier
+	}
+	uri := params.TextDocument.URI
+	key := diagnosticResultKey{identifier, uri}
+
+	self.lock.Lock()
+	result, ok := self.results[key]
+	self.lock.Unlock()
+
+	if !ok || (version == nil) || (result.version == nil) || (*result.version != *version) {
+		diagnostics, related, err := compute()
+		if err != nil {
+			return nil, err
+		}
+
+		if diagnostics == nil {
+			diagnostics = []protocol316.Diagnostic{}
+		}
+
+		result = &diagnosticResult{
+			resultID:    hashDiagnostics(diagnostics),
+			diagnostics: diagnostics,
+			related:     related,
+		}
+		if version != nil {
+			version_ := *version
+			result.version = &version_
+		}
+	}
+
+	relatedDocuments := relatedReports(result.related, previousResultIds)
+
+	self.lock.Lock()
+	self.results[key] = result
+	self.lock.Unlock()
+
+	if (params.PreviousResultId != nil) && (*params.PreviousResultId == result.resultID) {
+		return RelatedUnchangedDocumentDiagnosticReport{
+			UnchangedDocumentDiagnosticReport: UnchangedDocumentDiagnosticReport{
+				Kind:     string(DocumentDiagnosticReportKindUnchanged),
+				ResultID: result.resultID,
+			},
+			RelatedDocuments: relatedDocuments,
+		}, nil
+	}
+
+	resultID := result.resultID
+	return RelatedFullDocumentDiagnosticReport{
+		FullDocumentDiagnosticReport: FullDocumentDiagnosticReport{
+			Kind:     string(DocumentDiagnosticReportKindFull),
+			ResultID: &resultID,
+			Items:    result.diagnostics,
+		},
+		RelatedDocuments: relatedDocuments,
+	}, nil
+}
+
+// Call when the document is closed.
+func (self *DiagnosticResults) Forget(uri protocol316.DocumentUri) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for key := range self.results {
+		if key.uri == uri {
+			delete(self.results, key)
+		}
+	}
+}
+
+func relatedReports(related map[protocol316.DocumentUri][]protocol316.Diagnostic, previousResultIds []PreviousResultId) map[protocol316.DocumentUri]any {
+	if len(related) == 0 {
+		return nil
+	}
+
+	reports := make(map[protocol316.DocumentUri]any)
+	for uri, diagnostics := range related {
+		if diagnostics == nil {
+			diagnostics = []protocol316.Diagnostic{}
+		}
+		resultID := hashDiagnostics(diagnostics)
+
+		if hasPreviousResultId(previousResultIds, uri, resultID) {
+			reports[uri] = UnchangedDocumentDiagnosticReport{
+				Kind:     string(DocumentDiagnosticReportKindUnchanged),
+				ResultID: resultID,
+			}
+		} else {
+			reports[uri] = FullDocumentDiagnosticReport{
+				Kind:     string(DocumentDiagnosticReportKindFull),
+				ResultID: &resultID,
+				Items:    diagnostics,
+			}
+		}
+	}
+
+	return reports
+}
+
+func hasPreviousResultId(previousResultIds []PreviousResultId, uri protocol316.DocumentUri, resultID string) bool {
+	for _, previousResultId := range previousResultIds {
+		if (previousResultId.URI == uri) && (previousResultId.Value == resultID) {
+			return true
+		}
+	}
+	return false
+}
+
+func hashDiagnostics(diagnostics []protocol316.Diagnostic) string {
+	hash := sha256.New()
+	if err := json.NewEncoder(hash).Encode(diagnostics); err != nil {
+		// Should not happen, but if it does we will just never be unchanged
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
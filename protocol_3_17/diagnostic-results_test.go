@@ -0,0 +1,70 @@
+package protocol
+
+import (
+	"testing"
+
+	protocol316 "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestDiagnosticResultsRelated(t *testing.T) {
+	results := NewDiagnosticResults()
+
+	uri := protocol316.DocumentUri("file:///main.go")
+	relatedUri := protocol316.DocumentUri("file:///other.go")
+	compute := func() ([]protocol316.Diagnostic, map[protocol316.DocumentUri][]protocol316.Diagnostic, error) {
+		return []protocol316.Diagnostic{{Message: "main"}}, map[protocol316.DocumentUri][]protocol316.Diagnostic{
+			relatedUri: {{Message: "other"}},
+		}, nil
+	}
+
+	params := DocumentDiagnosticParams{TextDocument: protocol316.TextDocumentIdentifier{URI: uri}}
+
+	report, err := results.Report(&params, nil, compute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, ok := report.(RelatedFullDocumentDiagnosticReport)
+	if !ok {
+		t.Fatalf("expected a full report: %T", report)
+	}
+	relatedFull, ok := full.RelatedDocuments[relatedUri].(FullDocumentDiagnosticReport)
+	if !ok {
+		t.Fatalf("expected a full related report: %T", full.RelatedDocuments[relatedUri])
+	}
+
+	// We issued a result ID for the related document, but the client did not tell us it has it
+	params.PreviousResultId = full.ResultID
+	report, err = results.Report(&params, nil, compute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unchanged, ok := report.(RelatedUnchangedDocumentDiagnosticReport)
+	if !ok {
+		t.Fatalf("expected an unchanged report: %T", report)
+	}
+	if _, ok := unchanged.RelatedDocuments[relatedUri].(FullDocumentDiagnosticReport); !ok {
+		t.Errorf("expected a full related report: %T", unchanged.RelatedDocuments[relatedUri])
+	}
+
+	// Now it did
+	report, err = results.Report(&params, nil, compute, PreviousResultId{URI: relatedUri, Value: *relatedFull.ResultID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unchanged = report.(RelatedUnchangedDocumentDiagnosticReport)
+	if relatedUnchanged, ok := unchanged.RelatedDocuments[relatedUri].(UnchangedDocumentDiagnosticReport); !ok {
+		t.Errorf("expected an unchanged related report: %T", unchanged.RelatedDocuments[relatedUri])
+	} else if relatedUnchanged.ResultID != *relatedFull.ResultID {
+		t.Errorf("unexpected result ID: %s", relatedUnchanged.ResultID)
+	}
+
+	// A stale result ID is not unchanged
+	report, err = results.Report(&params, nil, compute, PreviousResultId{URI: relatedUri, Value: "stale"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unchanged = report.(RelatedUnchangedDocumentDiagnosticReport)
+	if _, ok := unchanged.RelatedDocuments[relatedUri].(FullDocumentDiagnosticReport); !ok {
+		t.Errorf("expected a full related report: %T", unchanged.RelatedDocuments[relatedUri])
+	}
+}
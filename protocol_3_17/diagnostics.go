@@ -1,6 +1,10 @@
 package protocol
 
 import (
+	contextpkg "context"
+	"errors"
+	"sync"
+
 	"github.com/tliron/glsp"
 	protocol316 "github.com/tliron/glsp/protocol_3_16"
 )
@@ -225,3 +229,309 @@ type DocumentDiagnosticReportPartialResult struct {
 type DiagnosticServerCancellationData struct {
 	RetriggerRequest bool `json:"retriggerRequest"`
 }
+
+// Creates a ServerCancelled error to be returned from a diagnostic request handler.
+// If retriggerRequest is true the client will re-trigger the request.
+func NewDiagnosticServerCancelledError(message string, retriggerRequest bool) *glsp.Error {
+	return glsp.NewErrorWithData(glsp.ErrorCodeServerCancelled, message, DiagnosticServerCancellationData{
+		RetriggerRequest: retriggerRequest,
+	})
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#workspace_diagnostic
+
+const MethodWorkspaceDiagnostic = protocol316.Method("workspace/diagnostic")
+
+type WorkspaceDiagnosticFunc func(context *glsp.Context, params *WorkspaceDiagnosticParams) (*WorkspaceDiagnosticReport, error)
+
+/**
+ * Parameters of the workspace diagnostic request.
+ *
+ * @since 3.17.0
+ */
+type WorkspaceDiagnosticParams struct {
+	protocol316.WorkDoneProgressParams
+	protocol316.PartialResultParams
+
+	/**
+	 * The additional identifier provided during registration.
+	 */
+	Identifier *string `json:"identifier,omitempty"`
+
+	/**
+	 * The currently known diagnostic reports with their
+	 * previous result ids.
+	 */
+	PreviousResultIds []PreviousResultId `json:"previousResultIds"`
+}
+
+/**
+ * A previous result id in a workspace pull request.
+ *
+ * @since 3.17.0
+ */
+type PreviousResultId struct {
+	/**
+	 * The URI for which the client knows a
+	 * result id.
+	 */
+	URI protocol316.DocumentUri `json:"uri"`
+
+	/**
+	 * The value of the previous result id.
+	 */
+	Value string `json:"value"`
+}
+
+/**
+ * A workspace diagnostic report.
+ *
+ * @since 3.17.0
+ */
+type WorkspaceDiagnosticReport struct {
+	Items []WorkspaceDocumentDiagnosticReport `json:"items"`
+}
+
+/**
+ * A full document diagnostic report for a workspace diagnostic result.
+ *
+ * @since 3.17.0
+ */
+type WorkspaceFullDocumentDiagnosticReport struct {
+	FullDocumentDiagnosticReport
+
+	/**
+	 * The URI for which diagnostic information is reported.
+	 */
+	URI protocol316.DocumentUri `json:"uri"`
+
+	/**
+	 * The version number for which the diagnostics are reported.
+	 * If the document is not marked as open `null` can be provided.
+	 */
+	Version *protocol316.Integer `json:"version"`
+}
+
+/**
+ * An unchanged document diagnostic report for a workspace diagnostic result.
+ *
+ * @since 3.17.0
+ */
+type WorkspaceUnchangedDocumentDiagnosticReport struct {
+	UnchangedDocumentDiagnosticReport
+
+	/**
+	 * The URI for which diagnostic information is reported.
+	 */
+	URI protocol316.DocumentUri `json:"uri"`
+
+	/**
+	 * The version number for which the diagnostics are reported.
+	 * If the document is not marked as open `null` can be provided.
+	 */
+	Version *protocol316.Integer `json:"version"`
+}
+
+/**
+ * A workspace diagnostic document report.
+ *
+ * @since 3.17.0
+ */
+type WorkspaceDocumentDiagnosticReport any // WorkspaceFullDocumentDiagnosticReport | WorkspaceUnchangedDocumentDiagnosticReport
+
+/**
+ * A partial result for a workspace diagnostic report.
+ *
+ * @since 3.17.0
+ */
+type WorkspaceDiagnosticReportPartialResult struct {
+	Items []WorkspaceDocumentDiagnosticReport `json:"items"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#diagnostic_refresh
+
+/**
+ * Workspace client capabilities specific to diagnostic pull requests.
+ *
+ * @since 3.17.0
+ */
+type DiagnosticWorkspaceClientCapabilities struct {
+	/**
+	 * Whether the client implementation supports a refresh request sent from
+	 * the server to the client.
+	 *
+	 * Note that this event is global and will force the client to refresh all
+	 * pulled diagnostics currently shown. It should be used with absolute care
+	 * and is useful for situation where a server for example detects a project
+	 * wide change that requires such a calculation.
+	 */
+	RefreshSupport *bool `json:"refreshSupport,omitempty"`
+}
+
+const ServerWorkspaceDiagnosticRefresh = protocol316.Method("workspace/diagnostic/refresh")
+
+// For "workspace/diagnostic". Return the Result as the report's Items.
+func NewWorkspaceDiagnosticPartialResultWriter(context *glsp.Context, params *WorkspaceDiagnosticParams) *protocol316.PartialResultWriter[WorkspaceDocumentDiagnosticReport] {
+	return protocol316.NewPartialResultWriterWithWrapper(context, params.PartialResultParams, func(chunk []WorkspaceDocumentDiagnosticReport) any {
+		return WorkspaceDiagnosticReportPartialResult{Items: chunk}
+	})
+}
+
+//
+// DocumentDiagnosticPartialResultWriter
+//
+
+// For "textDocument/diagnostic". Streams the reports of related documents to the client via
+// "$/progress", using the partial result token provided by the client. If the client did not
+// provide a token then they are buffered instead.
+//
+// The spec requires the first partial result to be the report for the requested document,
+// so call Report before Write. Either way the handler should return Result.
+type DocumentDiagnosticPartialResultWriter struct {
+	// Nil if the client did not provide a token, in which case we are buffering
+	Token *protocol316.ProgressToken
+
+	context          *glsp.Context
+	report           DocumentDiagnosticReport
+	relatedDocuments map[protocol316.DocumentUri]any
+	lock             sync.Mutex
+}
+
+func NewDocumentDiagnosticPartialResultWriter(context *glsp.Context, params *DocumentDiagnosticParams) *DocumentDiagnosticPartialResultWriter {
+	return &DocumentDiagnosticPartialResultWriter{
+		Token:   params.PartialResultToken,
+		context: context,
+	}
+}
+
+// Whether the reports are sent to the client rather than buffered.
+func (self *DocumentDiagnosticPartialResultWriter) Streaming() bool {
+	return self.Token != nil
+}
+
+// The report is a [RelatedFullDocumentDiagnosticReport] or a [RelatedUnchangedDocumentDiagnosticReport]
+// (or a pointer to either). It is sent to the client, or kept if there is no token.
+func (self *DocumentDiagnosticPartialResultWriter) Report(report DocumentDiagnosticReport) error {
+	self.lock.Lock()
+	self.report = report
+	self.lock.Unlock()
+
+	if self.Token == nil {
+		return nil
+	}
+
+	return self.progress(report)
+}
+
+// The values are [FullDocumentDiagnosticReport] or [UnchangedDocumentDiagnosticReport]. They
+// are sent to the client, or buffered if there is no token. Sending stops with an error if the
+// request is cancelled.
+func (self *DocumentDiagnosticPartialResultWriter) Write(relatedDocuments map[protocol316.DocumentUri]any) error {
+	if len(relatedDocuments) == 0 {
+		return nil
+	}
+
+	self.lock.Lock()
+	if self.Token == nil {
+		defer self.lock.Unlock()
+		if self.relatedDocuments == nil {
+			self.relatedDocuments = make(map[protocol316.DocumentUri]any)
+		}
+		for uri, report := range relatedDocuments {
+			self.relatedDocuments[uri] = report
+		}
+		return nil
+	}
+	reported := self.report != nil
+	self.lock.Unlock()
+
+	if !reported {
+		return errors.New("the document diagnostic report must be sent before related documents")
+	}
+
+	return self.progress(DocumentDiagnosticReportPartialResult{RelatedDocuments: relatedDocuments})
+}
+
+// The final result for the response. If we are buffering then it is the report with the
+// buffered related documents added to it. If we are streaming then everything was already
+// sent, so it is an empty report of the same kind (and with the same result ID), because
+// the spec requires the response to be empty when partial results are used.
+func (self *DocumentDiagnosticPartialResultWriter) Result() DocumentDiagnosticReport {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.Token != nil {
+		return emptyDocumentDiagnosticReport(self.report)
+	}
+
+	if len(self.relatedDocuments) == 0 {
+		return self.report
+	}
+
+	// We add to copies so as not to modify the caller's report
+	switch report := self.report.(type) {
+	case RelatedFullDocumentDiagnosticReport:
+		report.RelatedDocuments = mergeRelatedDocuments(report.RelatedDocuments, self.relatedDocuments)
+		return report
+	case *RelatedFullDocumentDiagnosticReport:
+		report_ := *report
+		report_.RelatedDocuments = mergeRelatedDocuments(report.RelatedDocuments, self.relatedDocuments)
+		return &report_
+	case RelatedUnchangedDocumentDiagnosticReport:
+		report.RelatedDocuments = mergeRelatedDocuments(report.RelatedDocuments, self.relatedDocuments)
+		return report
+	case *RelatedUnchangedDocumentDiagnosticReport:
+		report_ := *report
+		report_.RelatedDocuments = mergeRelatedDocuments(report.RelatedDocuments, self.relatedDocuments)
+		return &report_
+	default:
+		return self.report
+	}
+}
+
+func (self *DocumentDiagnosticPartialResultWriter) progress(value any) error {
+	context := self.context.Context
+	if context == nil {
+		context = contextpkg.Background()
+	}
+
+	return self.context.NotifyWithContext(context, protocol316.MethodProgress, &protocol316.ProgressParams{
+		Token: *self.Token,
+		Value: value,
+	})
+}
+
+// Without items and related documents.
+func emptyDocumentDiagnosticReport(report DocumentDiagnosticReport) DocumentDiagnosticReport {
+	switch report := report.(type) {
+	case RelatedFullDocumentDiagnosticReport:
+		return RelatedFullDocumentDiagnosticReport{FullDocumentDiagnosticReport: emptyFullDocumentDiagnosticReport(report.FullDocumentDiagnosticReport)}
+	case *RelatedFullDocumentDiagnosticReport:
+		return &RelatedFullDocumentDiagnosticReport{FullDocumentDiagnosticReport: emptyFullDocumentDiagnosticReport(report.FullDocumentDiagnosticReport)}
+	case RelatedUnchangedDocumentDiagnosticReport:
+		return RelatedUnchangedDocumentDiagnosticReport{UnchangedDocumentDiagnosticReport: report.UnchangedDocumentDiagnosticReport}
+	case *RelatedUnchangedDocumentDiagnosticReport:
+		return &RelatedUnchangedDocumentDiagnosticReport{UnchangedDocumentDiagnosticReport: report.UnchangedDocumentDiagnosticReport}
+	default:
+		return report
+	}
+}
+
+func emptyFullDocumentDiagnosticReport(report FullDocumentDiagnosticReport) FullDocumentDiagnosticReport {
+	return FullDocumentDiagnosticReport{
+		Kind:     report.Kind,
+		ResultID: report.ResultID,
+		Items:    []protocol316.Diagnostic{},
+	}
+}
+
+func mergeRelatedDocuments(a map[protocol316.DocumentUri]any, b map[protocol316.DocumentUri]any) map[protocol316.DocumentUri]any {
+	merged := make(map[protocol316.DocumentUri]any, len(a)+len(b))
+	for uri, report := range a {
+		merged[uri] = report
+	}
+	for uri, report := range b {
+		merged[uri] = report
+	}
+	return merged
+}
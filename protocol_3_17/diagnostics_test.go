@@ -0,0 +1,124 @@
+package protocol
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"testing"
+
+	"github.com/tliron/glsp"
+	protocol316 "github.com/tliron/glsp/protocol_3_16"
+)
+
+func newTestDocumentDiagnosticPartialResultWriter(token *protocol316.ProgressToken) (*DocumentDiagnosticPartialResultWriter, *[]string) {
+	var values []string
+	context := glsp.Context{
+		NotifyWithContext: func(context contextpkg.Context, method string, params any) error {
+			if value, err := json.Marshal(params.(*protocol316.ProgressParams).Value); err == nil {
+				values = append(values, method+" "+string(value))
+			} else {
+				return err
+			}
+			return nil
+		},
+	}
+
+	params := DocumentDiagnosticParams{PartialResultParams: protocol316.PartialResultParams{PartialResultToken: token}}
+	return NewDocumentDiagnosticPartialResultWriter(&context, &params), &values
+}
+
+func marshalTestReport(t *testing.T, report any) string {
+	if data, err := json.Marshal(report); err == nil {
+		return string(data)
+	} else {
+		t.Fatal(err)
+		return ""
+	}
+}
+
+func TestDocumentDiagnosticPartialResultWriterStreaming(t *testing.T) {
+	resultID := "1"
+	related := map[protocol316.DocumentUri]any{
+		"file:///other.go": UnchangedDocumentDiagnosticReport{Kind: "unchanged", ResultID: "2"},
+	}
+
+	tests := []struct {
+		report   DocumentDiagnosticReport
+		streamed string
+		result   string
+	}{
+		{
+			RelatedFullDocumentDiagnosticReport{
+				FullDocumentDiagnosticReport: FullDocumentDiagnosticReport{Kind: "full", ResultID: &resultID, Items: []protocol316.Diagnostic{{Message: "main"}}},
+				RelatedDocuments:             related,
+			},
+			`$/progress {"kind":"full","resultId":"1","items":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"message":"main"}],"relatedDocuments":{"file:///other.go":{"kind":"unchanged","resultId":"2"}}}`,
+			`{"kind":"full","resultId":"1","items":[]}`,
+		},
+		{
+			&RelatedUnchangedDocumentDiagnosticReport{
+				UnchangedDocumentDiagnosticReport: UnchangedDocumentDiagnosticReport{Kind: "unchanged", ResultID: "1"},
+				RelatedDocuments:                  related,
+			},
+			`$/progress {"kind":"unchanged","resultId":"1","relatedDocuments":{"file:///other.go":{"kind":"unchanged","resultId":"2"}}}`,
+			`{"kind":"unchanged","resultId":"1"}`,
+		},
+	}
+
+	for _, test := range tests {
+		writer, values := newTestDocumentDiagnosticPartialResultWriter(&protocol316.ProgressToken{Value: "token"})
+
+		if err := writer.Write(related); err == nil {
+			t.Error("expected an error for writing before reporting")
+		}
+		if err := writer.Report(test.report); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Write(map[protocol316.DocumentUri]any{
+			"file:///another.go": FullDocumentDiagnosticReport{Kind: "full", Items: []protocol316.Diagnostic{}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []string{
+			test.streamed,
+			`$/progress {"relatedDocuments":{"file:///another.go":{"kind":"full","items":[]}}}`,
+		}
+		if (len(*values) != len(expected)) || ((*values)[0] != expected[0]) || ((*values)[1] != expected[1]) {
+			t.Errorf("streamed:\n%v\nexpected:\n%v", *values, expected)
+		}
+
+		result := writer.Result()
+		if data := marshalTestReport(t, result); data != test.result {
+			t.Errorf("result: got %s, expected %s", data, test.result)
+		}
+
+		// The caller's report is not modified
+		if data := marshalTestReport(t, test.report); data != test.streamed[len("$/progress "):] {
+			t.Errorf("report modified: %s", data)
+		}
+	}
+}
+
+func TestDocumentDiagnosticPartialResultWriterBuffering(t *testing.T) {
+	writer, values := newTestDocumentDiagnosticPartialResultWriter(nil)
+
+	if err := writer.Report(&RelatedFullDocumentDiagnosticReport{
+		FullDocumentDiagnosticReport: FullDocumentDiagnosticReport{Kind: "full", Items: []protocol316.Diagnostic{}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Write(map[protocol316.DocumentUri]any{
+		"file:///other.go": UnchangedDocumentDiagnosticReport{Kind: "unchanged", ResultID: "2"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*values) != 0 {
+		t.Errorf("streamed while buffering: %v", *values)
+	}
+
+	expected := `{"kind":"full","items":[],"relatedDocuments":{"file:///other.go":{"kind":"unchanged","resultId":"2"}}}`
+	if data := marshalTestReport(t, writer.Result()); data != expected {
+		t.Errorf("result: got %s, expected %s", data, expected)
+	}
+}
@@ -23,10 +23,111 @@ type InitializeParams struct {
 	Capabilities ClientCapabilities `json:"capabilities"`
 }
 
+// ([json.Unmarshaler] interface)
+func (self *InitializeParams) UnmarshalJSON(data []byte) error {
+	type initializeParams InitializeParams
+	var value initializeParams
+	if err := json.Unmarshal(data, &value); err == nil {
+		// Otherwise the shadowed 3.16 capabilities would remain empty
+		value.InitializeParams.Capabilities = value.Capabilities.ClientCapabilities
+		*self = InitializeParams(value)
+		return nil
+	} else {
+		return err
+	}
+}
+
 type ClientCapabilities struct {
 	protocol316.ClientCapabilities
 
+	/**
+	 * Workspace specific client capabilities.
+	 */
+	Workspace *WorkspaceClientCapabilities `json:"workspace,omitempty"`
+
 	TextDocument *TextDocumentClientCapabilities `json:"textDocument,omitempty"`
+
+	/**
+	 * General client capabilities.
+	 *
+	 * @since 3.16.0
+	 */
+	General *GeneralClientCapabilities `json:"general,omitempty"`
+}
+
+// ([json.Unmarshaler] interface)
+func (self *ClientCapabilities) UnmarshalJSON(data []byte) error {
+	type clientCapabilities ClientCapabilities
+	var value clientCapabilities
+	if err := json.Unmarshal(data, &value); err == nil {
+		// Otherwise the shadowed 3.16 fields would remain empty, and the promoted 3.16
+		// methods would not work
+		if err := json.Unmarshal(data, &value.ClientCapabilities); err == nil {
+			*self = ClientCapabilities(value)
+			return nil
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+/**
+ * General client capabilities.
+ *
+ * @since 3.16.0
+ */
+type GeneralClientCapabilities struct {
+	/**
+	 * Client capabilities specific to regular expressions.
+	 *
+	 * @since 3.16.0
+	 */
+	RegularExpressions *protocol316.RegularExpressionsClientCapabilities `json:"regularExpressions,omitempty"`
+
+	/**
+	 * Client capabilities specific to the client's markdown parser.
+	 *
+	 * @since 3.16.0
+	 */
+	Markdown *protocol316.MarkdownClientCapabilities `json:"markdown,omitempty"`
+
+	/**
+	 * The position encodings supported by the client. Client and server
+	 * have to agree on the same position encoding to ensure that offsets
+	 * (e.g. character position in a line) are interpreted the same on both
+	 * side.
+	 *
+	 * To keep the protocol backwards compatible the following applies: if
+	 * the value 'utf-16' is missing from the array of position encodings
+	 * servers can assume that the client supports UTF-16. UTF-16 is
+	 * therefore a mandatory encoding.
+	 *
+	 * If omitted it defaults to ['utf-16'].
+	 *
+	 * Implementation considerations: since the conversion from one encoding
+	 * into another requires the content of the file / line the conversion
+	 * is best done where the file is read which is usually on the server
+	 * side.
+	 *
+	 * @since 3.17.0
+	 */
+	PositionEncodings []PositionEncodingKind `json:"positionEncodings,omitempty"`
+}
+
+/**
+ * Workspace specific client capabilities.
+ */
+type WorkspaceClientCapabilities struct {
+	protocol316.WorkspaceClientCapabilities
+
+	/**
+	 * Client workspace capabilities specific to diagnostics.
+	 *
+	 * @since 3.17.0.
+	 */
+	Diagnostics *DiagnosticWorkspaceClientCapabilities `json:"diagnostics,omitempty"`
 }
 
 /**
@@ -46,6 +147,19 @@ type TextDocumentClientCapabilities struct {
 type ServerCapabilities struct {
 	protocol316.ServerCapabilities
 
+	/**
+	 * The position encoding the server picked from the encodings offered
+	 * by the client via the client capability `general.positionEncodings`.
+	 *
+	 * If the client didn't provide any position encodings the only valid
+	 * value that a server can return is 'utf-16'.
+	 *
+	 * If omitted it defaults to 'utf-16'.
+	 *
+	 * @since 3.17.0
+	 */
+	PositionEncoding *PositionEncodingKind `json:"positionEncoding,omitempty"`
+
 	/**
 	 * The server has support for pull model diagnostics.
 	 *
@@ -86,6 +200,7 @@ func (self *ServerCapabilities) UnmarshalJSON(data []byte) error {
 		Workspace                        *protocol316.ServerCapabilitiesWorkspace     `json:"workspace,omitempty"`
 		Experimental                     *any                                         `json:"experimental,omitempty"`
 		DiagnosticProvider               json.RawMessage                              `json:"diagnosticProvider,omitempty"` // nil | DiagnosticOptions | DiagnosticRegistrationOptions
+		PositionEncoding                 *PositionEncodingKind                        `json:"positionEncoding,omitempty"`
 	}
 
 	if err := json.Unmarshal(data, &value); err == nil {
@@ -96,6 +211,7 @@ func (self *ServerCapabilities) UnmarshalJSON(data []byte) error {
 		self.DocumentOnTypeFormattingProvider = value.DocumentOnTypeFormattingProvider
 		self.ExecuteCommandProvider = value.ExecuteCommandProvider
 		self.Workspace = value.Workspace
+		self.PositionEncoding = value.PositionEncoding
 
 		if value.TextDocumentSync != nil {
 			var value_ protocol316.TextDocumentSyncOptions
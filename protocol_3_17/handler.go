@@ -2,7 +2,6 @@ package protocol
 
 import (
 	"encoding/json"
-	"errors"
 	"sync"
 
 	"github.com/tliron/glsp"
@@ -14,6 +13,7 @@ type Handler struct {
 
 	Initialize             InitializeFunc
 	TextDocumentDiagnostic TextDocumentDiagnosticFunc
+	WorkspaceDiagnostic    WorkspaceDiagnosticFunc // only advertised if TextDocumentDiagnostic is also set
 
 	initialized bool
 	lock        sync.Mutex
@@ -21,16 +21,17 @@ type Handler struct {
 
 func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, validParams bool, err error) {
 	if !self.IsInitialized() && (context.Method != protocol316.MethodInitialize) {
-		return nil, true, true, errors.New("server not initialized")
+		return nil, true, true, glsp.NewError(glsp.ErrorCodeServerNotInitialized, "server not initialized")
 	}
 
 	switch context.Method {
 	case protocol316.MethodCancelRequest:
-		if self.CancelRequest != nil {
-			validMethod = true
-			var params protocol316.CancelParams
-			if err = json.Unmarshal(context.Params, &params); err == nil {
-				validParams = true
+		// Always handled, because the server cancels the request itself
+		validMethod = true
+		var params protocol316.CancelParams
+		if err = json.Unmarshal(context.Params, &params); err == nil {
+			validParams = true
+			if self.CancelRequest != nil {
 				err = self.CancelRequest(context, &params)
 			}
 		}
@@ -53,6 +54,7 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 			var params InitializeParams
 			if err = json.Unmarshal(context.Params, &params); err == nil {
 				validParams = true
+				SetInitializeParams(context, &params)
 				if r, err = self.Initialize(context, &params); err == nil {
 					self.SetInitialized(true)
 				}
@@ -108,11 +110,13 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 	// Window
 
 	case protocol316.MethodWindowWorkDoneProgressCancel:
-		if self.WindowWorkDoneProgressCancel != nil {
-			validMethod = true
-			var params protocol316.WorkDoneProgressCancelParams
-			if err = json.Unmarshal(context.Params, &params); err == nil {
-				validParams = true
+		// Always handled, for [protocol316.ProgressReporter]
+		validMethod = true
+		var params protocol316.WorkDoneProgressCancelParams
+		if err = json.Unmarshal(context.Params, &params); err == nil {
+			validParams = true
+			protocol316.CancelWorkDoneProgress(context, params.Token)
+			if self.WindowWorkDoneProgressCancel != nil {
 				err = self.WindowWorkDoneProgressCancel(context, &params)
 			}
 		}
@@ -282,11 +286,13 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 		}
 
 	case protocol316.MethodTextDocumentDidClose:
-		if self.TextDocumentDidClose != nil {
-			validMethod = true
-			var params protocol316.DidCloseTextDocumentParams
-			if err = json.Unmarshal(context.Params, &params); err == nil {
-				validParams = true
+		// Always handled, for [protocol316.SemanticTokensCache]
+		validMethod = true
+		var params protocol316.DidCloseTextDocumentParams
+		if err = json.Unmarshal(context.Params, &params); err == nil {
+			validParams = true
+			self.SemanticTokensCache().Forget(params.TextDocument.URI)
+			if self.TextDocumentDidClose != nil {
 				err = self.TextDocumentDidClose(context, &params)
 			}
 		}
@@ -589,7 +595,18 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 			var params protocol316.SemanticTokensParams
 			if err = json.Unmarshal(context.Params, &params); err == nil {
 				validParams = true
-				r, err = self.TextDocumentSemanticTokensFull(context, &params)
+				if self.TextDocumentSemanticTokensFullDelta == nil {
+					// Automatic delta support needs the full result, so we can't stream
+					params.PartialResultToken = nil
+				}
+				var tokens *protocol316.SemanticTokens
+				if tokens, err = self.TextDocumentSemanticTokensFull(context, &params); err == nil {
+					if self.TextDocumentSemanticTokensFullDelta == nil {
+						// Automatic delta support
+						tokens = self.SemanticTokensCache().Full(params.TextDocument.URI, tokens)
+					}
+					r = tokens
+				}
 			}
 		}
 
@@ -601,6 +618,20 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 				validParams = true
 				r, err = self.TextDocumentSemanticTokensFullDelta(context, &params)
 			}
+		} else if self.TextDocumentSemanticTokensFull != nil {
+			// Automatic delta support
+			validMethod = true
+			var params protocol316.SemanticTokensDeltaParams
+			if err = json.Unmarshal(context.Params, &params); err == nil {
+				validParams = true
+				var tokens *protocol316.SemanticTokens
+				if tokens, err = self.TextDocumentSemanticTokensFull(context, &protocol316.SemanticTokensParams{
+					WorkDoneProgressParams: params.WorkDoneProgressParams,
+					TextDocument:           params.TextDocument,
+				}); err == nil {
+					r = self.SemanticTokensCache().Delta(params.TextDocument.URI, params.PreviousResultID, tokens)
+				}
+			}
 		}
 
 	case protocol316.MethodTextDocumentSemanticTokensRange:
@@ -649,6 +680,16 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 			}
 		}
 
+	case MethodWorkspaceDiagnostic:
+		if self.WorkspaceDiagnostic != nil {
+			validMethod = true
+			var params WorkspaceDiagnosticParams
+			if err = json.Unmarshal(context.Params, &params); err == nil {
+				validParams = true
+				r, err = self.WorkspaceDiagnostic(context, &params)
+			}
+		}
+
 	default:
 		if self.CustomRequest != nil {
 			if handler, ok := self.CustomRequest[context.Method]; ok && (handler.Func != nil) {
@@ -661,8 +702,11 @@ func (self *Handler) Handle(context *glsp.Context) (r any, validMethod bool, val
 		}
 	}
 
-	return
+	if (err == nil) && (r != nil) {
+		r = protocol316.DowngradeResult(context.Method, context.Params, r, protocol316.GetClientCapabilities(context))
+	}
 
+	return
 }
 
 func (self *Handler) IsInitialized() bool {
@@ -813,12 +857,9 @@ func (self *Handler) CreateServerCapabilities() ServerCapabilities {
 		if _, ok := capabilities.SemanticTokensProvider.(*protocol316.SemanticTokensOptions); !ok {
 			capabilities.SemanticTokensProvider = &protocol316.SemanticTokensOptions{}
 		}
-		if self.TextDocumentSemanticTokensFullDelta != nil {
-			capabilities.SemanticTokensProvider.(*protocol316.SemanticTokensOptions).Full = &protocol316.SemanticDelta{}
-			capabilities.SemanticTokensProvider.(*protocol316.SemanticTokensOptions).Full.(*protocol316.SemanticDelta).Delta = &protocol316.True
-		} else {
-			capabilities.SemanticTokensProvider.(*protocol316.SemanticTokensOptions).Full = true
-		}
+		// Delta is always supported, if not by a handler then automatically
+		capabilities.SemanticTokensProvider.(*protocol316.SemanticTokensOptions).Full = &protocol316.SemanticDelta{}
+		capabilities.SemanticTokensProvider.(*protocol316.SemanticTokensOptions).Full.(*protocol316.SemanticDelta).Delta = &protocol316.True
 	}
 
 	if self.TextDocumentSemanticTokensRange != nil {
@@ -912,10 +953,11 @@ func (self *Handler) CreateServerCapabilities() ServerCapabilities {
 		}
 	}
 
+	// The workspace diagnostic request cannot be advertised on its own
 	if self.TextDocumentDiagnostic != nil {
 		capabilities.DiagnosticProvider = DiagnosticOptions{
 			InterFileDependencies: true,
-			WorkspaceDiagnostics:  false,
+			WorkspaceDiagnostics:  self.WorkspaceDiagnostic != nil,
 		}
 	}
 
@@ -0,0 +1,46 @@
+package server
+
+import (
+	contextpkg "context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (self *session) notify(context contextpkg.Context, connection *jsonrpc2.Conn, method string, params any) error {
+	if err := context.Err(); err != nil {
+		return err
+	}
+	return connection.Notify(context, method, params)
+}
+
+func (self *session) call(context contextpkg.Context, connection *jsonrpc2.Conn, method string, params any, result any) error {
+	// We pick our own IDs so that we can cancel the call
+	// (string IDs will not conflict with the default numeric IDs)
+	id := jsonrpc2.ID{
+		Str:      fmt.Sprintf("glsp-%d", atomic.AddUint64(&self.callCount, 1)),
+		IsString: true,
+	}
+
+	waiter, err := connection.DispatchCall(context, method, params, jsonrpc2.PickID(id))
+	if err != nil {
+		return err
+	}
+
+	if err := waiter.Wait(context, result); err != nil {
+		if context.Err() != nil {
+			// Let the client know that we are no longer interested
+			if err_ := connection.Notify(contextpkg.Background(), methodCancelRequest, cancelParams{id}); err_ != nil {
+				self.server.Log.Warningf("could not send %s: %s", methodCancelRequest, err_.Error())
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+type cancelParams struct {
+	ID jsonrpc2.ID `json:"id"`
+}
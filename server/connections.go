@@ -11,23 +11,23 @@ import (
 )
 
 func (self *Server) newStreamConnection(stream io.ReadWriteCloser) *jsonrpc2.Conn {
-	handler := self.newHandler()
+	session := self.newSession()
 	connectionOptions := self.newConnectionOptions()
 
 	context, cancel := contextpkg.WithTimeout(contextpkg.Background(), self.StreamTimeout)
 	defer cancel()
 
-	return jsonrpc2.NewConn(context, jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}), handler, connectionOptions...)
+	return session.attach(jsonrpc2.NewConn(context, jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}), session, connectionOptions...))
 }
 
 func (self *Server) newWebSocketConnection(socket *websocket.Conn) *jsonrpc2.Conn {
-	handler := self.newHandler()
+	session := self.newSession()
 	connectionOptions := self.newConnectionOptions()
 
 	context, cancel := contextpkg.WithTimeout(contextpkg.Background(), self.WebSocketTimeout)
 	defer cancel()
 
-	return jsonrpc2.NewConn(context, wsjsonrpc2.NewObjectStream(socket), handler, connectionOptions...)
+	return session.attach(jsonrpc2.NewConn(context, wsjsonrpc2.NewObjectStream(socket), session, connectionOptions...))
 }
 
 func (self *Server) newConnectionOptions() []jsonrpc2.ConnOpt {
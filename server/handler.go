@@ -2,7 +2,10 @@ package server
 
 import (
 	contextpkg "context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"runtime/debug"
 
 	"github.com/sourcegraph/jsonrpc2"
 	"github.com/tliron/glsp"
@@ -10,24 +13,44 @@ import (
 
 // See: https://github.com/sourcegraph/go-langserver/blob/master/langserver/handler.go#L206
 
-func (self *Server) newHandler() jsonrpc2.Handler {
-	return jsonrpc2.HandlerWithError(self.handle)
+const methodCancelRequest = "$/cancelRequest"
+
+func (self *session) handle(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+	result, err := self.dispatch(context, connection, request)
+
+	if !request.Notif && (context.Err() == contextpkg.Canceled) {
+		return nil, &jsonrpc2.Error{
+			Code:    glsp.ErrorCodeRequestCancelled,
+			Message: "request cancelled",
+		}
+	}
+
+	return result, err
 }
 
-func (self *Server) handle(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+func (self *session) dispatch(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
 	glspContext := glsp.Context{
 		Method: request.Method,
+		// Note that these use the session context rather than the request context,
+		// so that they may also be used after the handler returns
 		Notify: func(method string, params any) {
-			if err := connection.Notify(context, method, params); err != nil {
-				self.Log.Error(err.Error())
+			if err := self.notify(self.context, connection, method, params); err != nil {
+				self.server.Log.Error(err.Error())
 			}
 		},
 		Call: func(method string, params any, result any) {
-			if err := connection.Call(context, method, params, result); err != nil {
-				self.Log.Error(err.Error())
+			if err := self.call(self.context, connection, method, params, result); err != nil {
+				self.server.Log.Error(err.Error())
 			}
 		},
 		Context: context,
+		NotifyWithContext: func(context contextpkg.Context, method string, params any) error {
+			return self.notify(context, connection, method, params)
+		},
+		CallWithContext: func(context contextpkg.Context, method string, params any, result any) error {
+			return self.call(context, connection, method, params, result)
+		},
+		State: self.state,
 	}
 
 	if request.Params != nil {
@@ -37,14 +60,14 @@ func (self *Server) handle(context contextpkg.Context, connection *jsonrpc2.Conn
 	switch request.Method {
 	case "exit":
 		// We're giving the attached handler a chance to handle it first, but we'll ignore any result
-		self.Handler.Handle(&glspContext)
+		self.callHandler(&glspContext)
 		err := connection.Close()
 		return nil, err
 
 	default:
 		// Note: jsonrpc2 will not even call this function if reqest.Params is invalid JSON,
 		// so we don't need to handle jsonrpc2.CodeParseError here
-		result, validMethod, validParams, err := self.Handler.Handle(&glspContext)
+		result, validMethod, validParams, err := self.callHandler(&glspContext)
 		if !validMethod {
 			return nil, &jsonrpc2.Error{
 				Code:    jsonrpc2.CodeMethodNotFound,
@@ -62,12 +85,45 @@ func (self *Server) handle(context contextpkg.Context, connection *jsonrpc2.Conn
 				}
 			}
 		} else if err != nil {
-			return nil, &jsonrpc2.Error{
-				Code:    jsonrpc2.CodeInvalidRequest,
-				Message: err.Error(),
-			}
+			return nil, toResponseError(err)
 		} else {
 			return result, nil
 		}
 	}
 }
+
+// Recovers from panics in the handler, reporting them as [glsp.ErrorCodeInternalError]
+func (self *session) callHandler(context *glsp.Context) (result any, validMethod bool, validParams bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			self.server.Log.Errorf("panic while handling %s: %v\n%s", context.Method, r, debug.Stack())
+			result = nil
+			validMethod = true
+			validParams = true
+			err = glsp.NewErrorf(glsp.ErrorCodeInternalError, "panic while handling %s: %v", context.Method, r)
+		}
+	}()
+
+	return self.handler.Handle(context)
+}
+
+func toResponseError(err error) *jsonrpc2.Error {
+	var glspError *glsp.Error
+	if errors.As(err, &glspError) {
+		responseError := jsonrpc2.Error{
+			Code:    glspError.Code,
+			Message: glspError.Message,
+		}
+		if glspError.Data != nil {
+			if data, err := json.Marshal(glspError.Data); err == nil {
+				responseError.Data = (*json.RawMessage)(&data)
+			}
+		}
+		return &responseError
+	} else {
+		return &jsonrpc2.Error{
+			Code:    glsp.ErrorCodeInternalError,
+			Message: err.Error(),
+		}
+	}
+}
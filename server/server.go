@@ -14,7 +14,26 @@ var DefaultTimeout = time.Minute
 //
 
 type Server struct {
-	Handler     glsp.Handler
+	Handler glsp.Handler
+
+	// If set, will be called for every new connection to create a dedicated handler for it
+	// (instead of sharing Handler among all connections)
+	HandlerFactory HandlerFactory
+
+	// Will wrap the handler for every connection (the first is outermost)
+	Middleware []glsp.Middleware
+
+	// Messages are handled in the order in which they arrive, but not in the connection's
+	// read loop, so handlers (for requests and notifications alike) can make calls to the
+	// client and wait for the responses. Requests can be cancelled by the client with
+	// "$/cancelRequest" (via glsp.Context's Context).
+	//
+	// If Concurrent is false (the default) every message is handled to completion before the
+	// next one is. If true, requests are handled concurrently, each in its own goroutine, after
+	// the preceding notifications have been handled. Handlers must then be safe for concurrent
+	// use. Notifications are always handled one at a time.
+	Concurrent bool
+
 	LogBaseName string
 	Debug       bool
 
@@ -26,6 +45,8 @@ type Server struct {
 	WebSocketTimeout time.Duration
 }
 
+type HandlerFactory func() glsp.Handler
+
 func NewServer(handler glsp.Handler, logName string, debug bool) *Server {
 	return &Server{
 		Handler:          handler,
@@ -39,3 +60,23 @@ func NewServer(handler glsp.Handler, logName string, debug bool) *Server {
 		WebSocketTimeout: DefaultTimeout,
 	}
 }
+
+func NewServerWithHandlerFactory(handlerFactory HandlerFactory, logName string, debug bool) *Server {
+	server := NewServer(nil, logName, debug)
+	server.HandlerFactory = handlerFactory
+	return server
+}
+
+func (self *Server) Use(middleware ...glsp.Middleware) {
+	self.Middleware = append(self.Middleware, middleware...)
+}
+
+func (self *Server) getConnectionHandler() glsp.Handler {
+	var handler glsp.Handler
+	if self.HandlerFactory != nil {
+		handler = self.HandlerFactory()
+	} else {
+		handler = self.Handler
+	}
+	return glsp.Chain(handler, self.Middleware...)
+}
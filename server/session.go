@@ -0,0 +1,183 @@
+package server
+
+import (
+	contextpkg "context"
+	"encoding/json"
+	"sync"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/glsp"
+)
+
+//
+// session
+//
+
+// Per-connection state
+type session struct {
+	server  *Server
+	handler glsp.Handler
+
+	context contextpkg.Context
+	cancel  contextpkg.CancelFunc
+
+	requests     map[jsonrpc2.ID]contextpkg.CancelFunc
+	requestsLock sync.Mutex
+
+	state *glsp.State
+	queue *queue
+
+	callCount uint64
+}
+
+func (self *Server) newSession() *session {
+	context, cancel := contextpkg.WithCancel(contextpkg.Background())
+	return &session{
+		server:   self,
+		handler:  self.getConnectionHandler(),
+		context:  context,
+		cancel:   cancel,
+		requests: make(map[jsonrpc2.ID]contextpkg.CancelFunc),
+		state:    glsp.NewState(),
+		queue:    newQueue(),
+	}
+}
+
+// Cancels all in-flight requests when the connection is closed
+func (self *session) attach(connection *jsonrpc2.Conn) *jsonrpc2.Conn {
+	go func() {
+		<-connection.DisconnectNotify()
+		self.cancel()
+		self.queue.close()
+	}()
+	return connection
+}
+
+// Messages are handled in order on the session's queue rather than in the connection's read
+// loop, so that handlers can make calls to the client and receive the responses.
+//
+// ([jsonrpc2.Handler] interface)
+func (self *session) Handle(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) {
+	handler := jsonrpc2.HandlerWithError(self.handle)
+
+	if request.Notif {
+		if request.Method == methodCancelRequest {
+			// Immediately, because the request might be being handled right now
+			self.cancelRequest(request)
+		}
+		self.queue.add(func() {
+			handler.Handle(self.context, connection, request)
+		})
+		return
+	}
+
+	requestContext, cancel := contextpkg.WithCancel(self.context)
+	self.addRequest(request.ID, cancel)
+
+	handle := func() {
+		defer self.removeRequest(request.ID)
+		handler.Handle(requestContext, connection, request)
+	}
+
+	if self.server.Concurrent {
+		// Started only after the preceding notifications have been handled (e.g. text
+		// document changes), but doesn't hold up the following messages
+		self.queue.add(func() {
+			go handle()
+		})
+	} else {
+		self.queue.add(handle)
+	}
+}
+
+func (self *session) addRequest(id jsonrpc2.ID, cancel contextpkg.CancelFunc) {
+	self.requestsLock.Lock()
+	defer self.requestsLock.Unlock()
+	self.requests[id] = cancel
+}
+
+func (self *session) removeRequest(id jsonrpc2.ID) {
+	self.requestsLock.Lock()
+	defer self.requestsLock.Unlock()
+	if cancel, ok := self.requests[id]; ok {
+		cancel()
+		delete(self.requests, id)
+	}
+}
+
+func (self *session) cancelRequest(request *jsonrpc2.Request) {
+	if request.Params == nil {
+		return
+	}
+
+	var params cancelParams
+	if err := json.Unmarshal(*request.Params, &params); err != nil {
+		self.server.Log.Warningf("malformed %s: %s", methodCancelRequest, err.Error())
+		return
+	}
+
+	self.requestsLock.Lock()
+	defer self.requestsLock.Unlock()
+	if cancel, ok := self.requests[params.ID]; ok {
+		self.server.Log.Debugf("cancelling request: %s", params.ID.String())
+		cancel()
+	}
+}
+
+//
+// queue
+//
+
+// Runs functions one at a time, in order, on its own goroutine. It is unbounded so that
+// adding never blocks the connection's read loop.
+type queue struct {
+	functions []func()
+	closed    bool
+	lock      sync.Mutex
+	cond      *sync.Cond
+}
+
+func newQueue() *queue {
+	self := queue{}
+	self.cond = sync.NewCond(&self.lock)
+	go self.run()
+	return &self
+}
+
+// Ignored if the queue is closed.
+func (self *queue) add(function func()) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if !self.closed {
+		self.functions = append(self.functions, function)
+		self.cond.Signal()
+	}
+}
+
+// Functions that have not started yet are dropped.
+func (self *queue) close() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.closed = true
+	self.functions = nil
+	self.cond.Broadcast()
+}
+
+func (self *queue) run() {
+	for {
+		self.lock.Lock()
+		for (len(self.functions) == 0) && !self.closed {
+			self.cond.Wait()
+		}
+		if self.closed {
+			self.lock.Unlock()
+			return
+		}
+		function := self.functions[0]
+		self.functions[0] = nil
+		self.functions = self.functions[1:]
+		self.lock.Unlock()
+
+		function()
+	}
+}
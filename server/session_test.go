@@ -0,0 +1,136 @@
+package server
+
+import (
+	contextpkg "context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/glsp"
+)
+
+const testTimeout = 5 * time.Second
+
+// Returns the client's end of the connection. The client answers every request with its method.
+func connect(t *testing.T, server *Server) *jsonrpc2.Conn {
+	serverStream, clientStream := net.Pipe()
+
+	serverConnection := server.newStreamConnection(serverStream)
+	clientConnection := jsonrpc2.NewConn(contextpkg.Background(), jsonrpc2.NewBufferedStream(clientStream, jsonrpc2.VSCodeObjectCodec{}), jsonrpc2.HandlerWithError(func(context contextpkg.Context, connection *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+		return request.Method, nil
+	}))
+
+	t.Cleanup(func() {
+		clientConnection.Close()
+		serverConnection.Close()
+	})
+
+	return clientConnection
+}
+
+// The handler calls the client with the method it is handling and reports the result.
+func newCallingServer(concurrent bool, results chan<- string) *Server {
+	server := NewServer(glsp.HandlerFunc(func(context *glsp.Context) (any, bool, bool, error) {
+		context_, cancel := contextpkg.WithTimeout(contextpkg.Background(), testTimeout)
+		defer cancel()
+
+		var result string
+		if err := context.CallWithContext(context_, "client/"+context.Method, nil, &result); err != nil {
+			result = err.Error()
+		}
+		results <- result
+		return result, true, true, nil
+	}), "test", false)
+	server.Concurrent = concurrent
+	return server
+}
+
+func TestCallFromHandler(t *testing.T) {
+	for _, concurrent := range []bool{false, true} {
+		results := make(chan string, 2)
+		client := connect(t, newCallingServer(concurrent, results))
+
+		context, cancel := contextpkg.WithTimeout(contextpkg.Background(), testTimeout)
+		defer cancel()
+
+		if err := client.Notify(context, "initialized", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		var result string
+		if err := client.Call(context, "request", nil, &result); err != nil {
+			t.Fatalf("concurrent=%t: %s", concurrent, err.Error())
+		}
+
+		for _, expected := range []string{"client/initialized", "client/request"} {
+			select {
+			case result := <-results:
+				if result != expected {
+					t.Errorf("concurrent=%t: got %q, expected %q", concurrent, result, expected)
+				}
+			case <-context.Done():
+				t.Fatalf("concurrent=%t: timed out waiting for %q", concurrent, expected)
+			}
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	for _, concurrent := range []bool{false, true} {
+		methods := make(chan string, 10)
+		server := NewServer(glsp.HandlerFunc(func(context *glsp.Context) (any, bool, bool, error) {
+			if context.Method == "slow" {
+				time.Sleep(50 * time.Millisecond)
+			}
+			methods <- context.Method
+			return nil, true, true, nil
+		}), "test", false)
+		server.Concurrent = concurrent
+		client := connect(t, server)
+
+		context, cancel := contextpkg.WithTimeout(contextpkg.Background(), testTimeout)
+		defer cancel()
+
+		if err := client.Notify(context, "slow", nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Call(context, "request", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// The request must not start before the preceding notification has been handled
+		if first, second := <-methods, <-methods; (first != "slow") || (second != "request") {
+			t.Errorf("concurrent=%t: handled %q before %q", concurrent, first, second)
+		}
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	server := NewServer(glsp.HandlerFunc(func(context *glsp.Context) (any, bool, bool, error) {
+		if context.Method == "wait" {
+			<-context.Context.Done()
+		}
+		return nil, true, true, nil
+	}), "test", false)
+	client := connect(t, server)
+
+	context, cancel := contextpkg.WithTimeout(contextpkg.Background(), testTimeout)
+	defer cancel()
+
+	id := jsonrpc2.ID{Str: "wait", IsString: true}
+	waiter, err := client.DispatchCall(context, "wait", nil, jsonrpc2.PickID(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Notify(context, methodCancelRequest, cancelParams{id}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := waiter.Wait(context, nil); err == nil {
+		t.Error("expected the request to be cancelled")
+	} else if err_, ok := err.(*jsonrpc2.Error); !ok || (err_.Code != glsp.ErrorCodeRequestCancelled) {
+		t.Errorf("expected the request to be cancelled, got: %s", err.Error())
+	}
+}
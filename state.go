@@ -0,0 +1,50 @@
+package glsp
+
+import (
+	"sync"
+)
+
+//
+// State
+//
+
+// Values that are kept for the lifetime of a connection, shared by all its messages.
+// Keys should be of unexported types to avoid collisions (as with [context.Context]).
+type State struct {
+	values map[any]any
+	lock   sync.RWMutex
+}
+
+func NewState() *State {
+	return &State{values: make(map[any]any)}
+}
+
+func (self *State) Get(key any) (any, bool) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	value, ok := self.values[key]
+	return value, ok
+}
+
+func (self *State) Set(key any, value any) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.values[key] = value
+}
+
+// Returns the existing value if there is one, otherwise sets and returns the new value.
+func (self *State) GetOrSet(key any, value any) any {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if existing, ok := self.values[key]; ok {
+		return existing
+	}
+	self.values[key] = value
+	return value
+}
+
+func (self *State) Delete(key any) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	delete(self.values, key)
+}
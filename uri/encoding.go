@@ -0,0 +1,71 @@
+package uri
+
+import (
+	"fmt"
+	"strings"
+)
+
+const hex = "0123456789ABCDEF"
+
+// Like JavaScript's encodeURIComponent but keeps "/" in paths and "[", "]", ":" in
+// authorities, as does VS Code.
+func encode(component string, isPath bool, isAuthority bool) string {
+	var builder strings.Builder
+	for index := 0; index < len(component); index++ {
+		c := component[index]
+		if isUnreserved(c) || (isPath && (c == '/')) || (isAuthority && ((c == '[') || (c == ']') || (c == ':'))) {
+			builder.WriteByte(c)
+		} else {
+			builder.WriteByte('%')
+			builder.WriteByte(hex[c>>4])
+			builder.WriteByte(hex[c&0x0F])
+		}
+	}
+	return builder.String()
+}
+
+func decode(component string) (string, error) {
+	if !strings.Contains(component, "%") {
+		return component, nil
+	}
+
+	var builder strings.Builder
+	for index := 0; index < len(component); index++ {
+		c := component[index]
+		if c == '%' {
+			if index+2 >= len(component) {
+				return "", fmt.Errorf("malformed percent-encoding: %s", component)
+			}
+			high, ok := unhex(component[index+1])
+			if !ok {
+				return "", fmt.Errorf("malformed percent-encoding: %s", component)
+			}
+			low, ok := unhex(component[index+2])
+			if !ok {
+				return "", fmt.Errorf("malformed percent-encoding: %s", component)
+			}
+			builder.WriteByte(high<<4 | low)
+			index += 2
+		} else {
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String(), nil
+}
+
+func isUnreserved(c byte) bool {
+	return isASCIILetter(c) || ((c >= '0') && (c <= '9')) || (c == '-') || (c == '.') || (c == '_') || (c == '~')
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case (c >= '0') && (c <= '9'):
+		return c - '0', true
+	case (c >= 'a') && (c <= 'f'):
+		return c - 'a' + 10, true
+	case (c >= 'A') && (c <= 'F'):
+		return c - 'A' + 10, true
+	default:
+		return 0, false
+	}
+}
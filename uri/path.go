@@ -0,0 +1,93 @@
+package uri
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+type PathStyle int
+
+const (
+	PathStylePOSIX = PathStyle(iota)
+	PathStyleWindows
+)
+
+// The path style of the current platform
+var NativePathStyle = getNativePathStyle()
+
+// Supports Windows drive letters and UNC paths ("\\server\share\...") when style is
+// [PathStyleWindows]. Relative paths are treated as if they were absolute.
+func FromPath(path string, style PathStyle) URI {
+	if style == PathStyleWindows {
+		path = strings.ReplaceAll(path, "\\", "/")
+	}
+
+	var authority string
+	if (style == PathStyleWindows) && strings.HasPrefix(path, "//") {
+		// UNC
+		if index := strings.Index(path[2:], "/"); index != -1 {
+			authority = path[2 : index+2]
+			path = path[index+2:]
+		} else {
+			authority = path[2:]
+			path = "/"
+		}
+	}
+
+	if (path == "") || (path[0] != '/') {
+		path = "/" + path
+	}
+
+	return URI{
+		Scheme:    "file",
+		Authority: authority,
+		Path:      path,
+	}
+}
+
+// Returns the URI string.
+func PathToURI(path string, style PathStyle) string {
+	return FromPath(path, style).String()
+}
+
+// The URI must have the "file" scheme. Windows drive letters are lowercased.
+func (self URI) ToPath(style PathStyle) (string, error) {
+	if !self.IsFile() {
+		return "", fmt.Errorf("not a file URI: %s", self.String())
+	}
+
+	var path string
+	if (self.Authority != "") && (len(self.Path) > 1) {
+		// UNC
+		path = "//" + self.Authority + self.Path
+	} else if (style == PathStyleWindows) && (len(self.Path) >= 3) && (self.Path[0] == '/') && isASCIILetter(self.Path[1]) && (self.Path[2] == ':') {
+		// Drive letter
+		path = strings.ToLower(self.Path[1:2]) + self.Path[2:]
+	} else {
+		path = self.Path
+	}
+
+	if style == PathStyleWindows {
+		path = strings.ReplaceAll(path, "/", "\\")
+	}
+
+	return path, nil
+}
+
+// Parses the URI string, which must have the "file" scheme.
+func URIToPath(uri string, style PathStyle) (string, error) {
+	if uri_, err := Parse(uri); err == nil {
+		return uri_.ToPath(style)
+	} else {
+		return "", err
+	}
+}
+
+func getNativePathStyle() PathStyle {
+	if runtime.GOOS == "windows" {
+		return PathStyleWindows
+	} else {
+		return PathStylePOSIX
+	}
+}
@@ -0,0 +1,79 @@
+package uri
+
+import (
+	"testing"
+)
+
+func TestFromPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		style PathStyle
+		uri   string
+	}{
+		{"/home/user/file.go", PathStylePOSIX, "file:///home/user/file.go"},
+		{"/home/user/my file#1.go", PathStylePOSIX, "file:///home/user/my%20file%231.go"},
+		{"relative/file.go", PathStylePOSIX, "file:///relative/file.go"},
+		{"/", PathStylePOSIX, "file:///"},
+		{`C:\Users\user\file.go`, PathStyleWindows, "file:///c%3A/Users/user/file.go"},
+		{`c:\Users\user\file.go`, PathStyleWindows, "file:///c%3A/Users/user/file.go"},
+		{"C:/Users/user/file.go", PathStyleWindows, "file:///c%3A/Users/user/file.go"},
+		{`\\server\share\file.go`, PathStyleWindows, "file://server/share/file.go"},
+		{`\\server`, PathStyleWindows, "file://server/"},
+		{`C:\back\slash`, PathStylePOSIX, "file:///c%3A%5Cback%5Cslash"},
+	}
+
+	for _, test := range tests {
+		if uri := PathToURI(test.path, test.style); uri != test.uri {
+			t.Errorf("PathToURI(%q, %d) = %q, expected %q", test.path, test.style, uri, test.uri)
+		}
+	}
+}
+
+func TestToPath(t *testing.T) {
+	tests := []struct {
+		uri   string
+		style PathStyle
+		path  string
+	}{
+		{"file:///home/user/file.go", PathStylePOSIX, "/home/user/file.go"},
+		{"file:///home/user/my%20file%231.go", PathStylePOSIX, "/home/user/my file#1.go"},
+		{"file:///c%3A/Users/user/file.go", PathStylePOSIX, "/c:/Users/user/file.go"},
+		{"file:///c%3A/Users/user/file.go", PathStyleWindows, `c:\Users\user\file.go`},
+		{"file:///C:/Users/user/file.go", PathStyleWindows, `c:\Users\user\file.go`},
+		{"file://server/share/file.go", PathStyleWindows, `\\server\share\file.go`},
+		{"file://server/share/file.go", PathStylePOSIX, "//server/share/file.go"},
+		{"file:///home/user/file.go", PathStyleWindows, `\home\user\file.go`},
+	}
+
+	for _, test := range tests {
+		if path, err := URIToPath(test.uri, test.style); err != nil {
+			t.Errorf("URIToPath(%q, %d): %s", test.uri, test.style, err.Error())
+		} else if path != test.path {
+			t.Errorf("URIToPath(%q, %d) = %q, expected %q", test.uri, test.style, path, test.path)
+		}
+	}
+
+	if _, err := URIToPath("untitled:Untitled-1", PathStylePOSIX); err == nil {
+		t.Error("URIToPath of a non-file URI should fail")
+	}
+}
+
+func TestPathRoundTrip(t *testing.T) {
+	tests := []struct {
+		path  string
+		style PathStyle
+	}{
+		{"/home/user/my file (1).go", PathStylePOSIX},
+		{"/home/user/ü/日本.go", PathStylePOSIX},
+		{`c:\Users\user\my file.go`, PathStyleWindows},
+		{`\\server\share\dir\file.go`, PathStyleWindows},
+	}
+
+	for _, test := range tests {
+		if path, err := FromPath(test.path, test.style).ToPath(test.style); err != nil {
+			t.Errorf("%q: %s", test.path, err.Error())
+		} else if path != test.path {
+			t.Errorf("%q round-tripped to %q", test.path, path)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package uri
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// See: https://github.com/microsoft/vscode-uri/blob/main/src/uri.ts
+
+var uriRegexp = regexp.MustCompile(`^(([^:/?#]+?):)?(\/\/([^/?#]*))?([^?#]*)(\?([^#]*))?(#(.*))?`)
+
+var schemeRegexp = regexp.MustCompile(`^\w[\w\d+.-]*$`)
+
+//
+// URI
+//
+
+// The components are stored decoded (without percent-encoding).
+type URI struct {
+	Scheme    string
+	Authority string
+	Path      string
+	Query     string
+	Fragment  string
+}
+
+// Supports non-file schemes, e.g. "untitled:Untitled-1" and
+// "vscode-notebook-cell:/path/notebook.ipynb#W0sZmlsZQ%3D%3D".
+func Parse(uri string) (URI, error) {
+	matches := uriRegexp.FindStringSubmatch(uri)
+	if matches == nil {
+		return URI{}, fmt.Errorf("malformed URI: %s", uri)
+	}
+
+	var self URI
+	var err error
+
+	self.Scheme = matches[2]
+	if !schemeRegexp.MatchString(self.Scheme) {
+		return URI{}, fmt.Errorf("malformed URI scheme: %s", uri)
+	}
+
+	if self.Authority, err = decode(matches[4]); err != nil {
+		return URI{}, err
+	}
+	if self.Path, err = decode(matches[5]); err != nil {
+		return URI{}, err
+	}
+	if self.Query, err = decode(matches[7]); err != nil {
+		return URI{}, err
+	}
+	if self.Fragment, err = decode(matches[9]); err != nil {
+		return URI{}, err
+	}
+
+	if self.Scheme == "file" {
+		// Paths of file URIs are always absolute
+		if (self.Path == "") || (self.Path[0] != '/') {
+			self.Path = "/" + self.Path
+		}
+	}
+
+	if (self.Authority != "") && (self.Path != "") && (self.Path[0] != '/') {
+		return URI{}, fmt.Errorf("malformed URI, path must be absolute if there is an authority: %s", uri)
+	}
+
+	return self, nil
+}
+
+func (self URI) IsFile() bool {
+	return self.Scheme == "file"
+}
+
+// Formats the URI with the same normalization and percent-encoding as VS Code,
+// including lowercasing Windows drive letters.
+//
+// ([fmt.Stringer] interface)
+func (self URI) String() string {
+	var builder strings.Builder
+
+	if self.Scheme != "" {
+		builder.WriteString(self.Scheme)
+		builder.WriteRune(':')
+	}
+
+	if (self.Authority != "") || (self.Scheme == "file") {
+		builder.WriteString("//")
+	}
+
+	if self.Authority != "" {
+		authority := self.Authority
+		if index := strings.LastIndex(authority, "@"); index != -1 {
+			// User info
+			userInfo := authority[:index]
+			authority = authority[index+1:]
+			if index := strings.Index(userInfo, ":"); index != -1 {
+				builder.WriteString(encode(userInfo[:index], false, true))
+				builder.WriteRune(':')
+				builder.WriteString(encode(userInfo[index+1:], false, true))
+			} else {
+				builder.WriteString(encode(userInfo, false, true))
+			}
+			builder.WriteRune('@')
+		}
+
+		authority = strings.ToLower(authority)
+		if index := strings.LastIndex(authority, ":"); index != -1 {
+			// Port
+			builder.WriteString(encode(authority[:index], false, true))
+			builder.WriteString(authority[index:])
+		} else {
+			builder.WriteString(encode(authority, false, true))
+		}
+	}
+
+	if self.Path != "" {
+		builder.WriteString(encode(lowerDriveLetter(self.Path), true, false))
+	}
+
+	if self.Query != "" {
+		builder.WriteRune('?')
+		builder.WriteString(encode(self.Query, false, false))
+	}
+
+	if self.Fragment != "" {
+		builder.WriteRune('#')
+		builder.WriteString(encode(self.Fragment, false, false))
+	}
+
+	return builder.String()
+}
+
+// Returns the URI in the form that VS Code would send it.
+func Normalize(uri string) (string, error) {
+	if uri_, err := Parse(uri); err == nil {
+		return uri_.String(), nil
+	} else {
+		return "", err
+	}
+}
+
+// Compares after normalization. Malformed URIs are compared as is.
+func Equal(a string, b string) bool {
+	if a == b {
+		return true
+	}
+
+	a_, err := Normalize(a)
+	if err != nil {
+		return false
+	}
+
+	b_, err := Normalize(b)
+	if err != nil {
+		return false
+	}
+
+	return a_ == b_
+}
+
+// Utils
+
+// "/C:/..." or "C:/..."
+func lowerDriveLetter(path string) string {
+	if (len(path) >= 3) && (path[0] == '/') && (path[2] == ':') && isUpperASCIILetter(path[1]) {
+		return "/" + strings.ToLower(path[1:2]) + path[2:]
+	} else if (len(path) >= 2) && (path[1] == ':') && isUpperASCIILetter(path[0]) {
+		return strings.ToLower(path[0:1]) + path[1:]
+	}
+	return path
+}
+
+func isASCIILetter(c byte) bool {
+	return ((c >= 'a') && (c <= 'z')) || ((c >= 'A') && (c <= 'Z'))
+}
+
+func isUpperASCIILetter(c byte) bool {
+	return (c >= 'A') && (c <= 'Z')
+}
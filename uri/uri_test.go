@@ -0,0 +1,75 @@
+package uri
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		uri        string
+		normalized string
+	}{
+		// Drive letters
+		{"file:///C:/Users/file.go", "file:///c%3A/Users/file.go"},
+		{"file:///c:/Users/file.go", "file:///c%3A/Users/file.go"},
+		{"file:///C%3A/Users/file.go", "file:///c%3A/Users/file.go"},
+		{"file:///c%3a/Users/file.go", "file:///c%3A/Users/file.go"},
+		{"file:///C/Users/file.go", "file:///C/Users/file.go"},
+
+		// Percent-encoding
+		{"file:///home/my%20file.go", "file:///home/my%20file.go"},
+		{"file:///home/my file.go", "file:///home/my%20file.go"},
+		{"file:///home/%7Euser/file.go", "file:///home/~user/file.go"},
+		{"file:///home/%c3%bc.go", "file:///home/%C3%BC.go"},
+		{"file:///home/ü.go", "file:///home/%C3%BC.go"},
+		{"file:///home/a%2Fb.go", "file:///home/a/b.go"},
+
+		// Authorities
+		{"file://SERVER/share/file.go", "file://server/share/file.go"},
+		{"http://User@Example.com:8080/a?b=c#d", "http://User@example.com:8080/a?b%3Dc#d"},
+
+		// Other schemes
+		{"untitled:Untitled-1", "untitled:Untitled-1"},
+		{"vscode-notebook-cell:/path/notebook.ipynb#W0sZmlsZQ%3D%3D", "vscode-notebook-cell:/path/notebook.ipynb#W0sZmlsZQ%3D%3D"},
+	}
+
+	for _, test := range tests {
+		if normalized, err := Normalize(test.uri); err != nil {
+			t.Errorf("Normalize(%q): %s", test.uri, err.Error())
+		} else if normalized != test.normalized {
+			t.Errorf("Normalize(%q) = %q, expected %q", test.uri, normalized, test.normalized)
+		}
+	}
+}
+
+func TestNormalizeMalformed(t *testing.T) {
+	for _, uri := range []string{
+		"file:///home/%zz.go",
+		"file:///home/%2",
+		"a b:c",
+	} {
+		if _, err := Normalize(uri); err == nil {
+			t.Errorf("Normalize(%q) should fail", uri)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a     string
+		b     string
+		equal bool
+	}{
+		{"file:///C:/file.go", "file:///c%3A/file.go", true},
+		{"file:///home/my%20file.go", "file:///home/my file.go", true},
+		{"file:///home/File.go", "file:///home/file.go", false},
+		{"file:///home/%zz.go", "file:///home/%zz.go", true},
+		{"file:///home/%zz.go", "file:///home/zz.go", false},
+	}
+
+	for _, test := range tests {
+		if equal := Equal(test.a, test.b); equal != test.equal {
+			t.Errorf("Equal(%q, %q) = %t, expected %t", test.a, test.b, equal, test.equal)
+		}
+	}
+}